@@ -2,18 +2,33 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/manuzokas/subscription-api/internal/adapters/database"
+	"github.com/manuzokas/subscription-api/internal/adapters/gateway"
 	"github.com/manuzokas/subscription-api/internal/adapters/messaging"
+	"github.com/manuzokas/subscription-api/internal/adapters/pdf"
 	"github.com/manuzokas/subscription-api/internal/adapters/web"
+	"github.com/manuzokas/subscription-api/internal/config"
 	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/core/coupon"
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+	"github.com/manuzokas/subscription-api/internal/core/invoice"
+	"github.com/manuzokas/subscription-api/internal/core/outbox"
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+	"github.com/manuzokas/subscription-api/internal/core/plan"
 	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -40,6 +55,17 @@ func main() {
 	defer pool.Close()
 	log.Println("Successfully connected to the database!")
 
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		migrator, err := database.NewMigrator(pool)
+		if err != nil {
+			log.Fatalf("Unable to load migrations: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Unable to run migrations: %v", err)
+		}
+		log.Println("Database migrations are up to date.")
+	}
+
 	publisher, err := messaging.NewRabbitMQPublisher(amqpURL)
 	if err != nil {
 		log.Fatalf("Unable to connect to RabbitMQ: %v", err)
@@ -49,19 +75,98 @@ func main() {
 
 	subRepo := database.NewPostgresRepository(pool)
 	userRepo := database.NewPostgresUserRepository(pool)
+	planRepo := database.NewPostgresPlanRepository(pool)
+	couponRepo := database.NewPostgresCouponRepository(pool)
+	outboxRepo := database.NewPostgresOutboxRepository(pool)
+	tokenRepo := database.NewPostgresTokenRepository(pool)
+	idempotencyRepo := database.NewPostgresIdempotencyRepository(pool)
+	invoiceRepo := database.NewPostgresInvoiceRepository(pool)
+
+	prometheus.MustRegister(database.NewPoolCollector(pool), database.NewSubscriptionStatusCollector(subRepo))
 
-	subService := subscription.NewService(subRepo, userRepo, publisher)
-	authService := auth.NewAuthService(userRepo)
+	paymentGateway, err := gateway.New(os.Getenv("PAYMENT_GATEWAY"))
+	if err != nil {
+		log.Fatalf("Unable to configure the payment gateway: %v", err)
+	}
+	paymentService := payment.NewService(paymentGateway, userRepo)
+	subService := subscription.NewService(subRepo, userRepo, planRepo, couponRepo, paymentService, subscription.BillingConfig{
+		TaxRateBasisPoints: config.IntFromEnv("INVOICE_TAX_RATE_BPS", 0),
+	})
+	authService := auth.NewAuthService(userRepo, tokenRepo, auth.TokenConfig{
+		JWTSecret:       jwtSecret,
+		AccessTokenTTL:  config.DurationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: config.DurationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+	})
+	planService := plan.NewService(planRepo)
+	couponService := coupon.NewService(couponRepo, planRepo)
+	invoiceService := invoice.NewService(invoiceRepo, userRepo, pdf.NewInvoiceRenderer(pdf.Company{
+		Name:    os.Getenv("INVOICE_COMPANY_NAME"),
+		Address: os.Getenv("INVOICE_COMPANY_ADDRESS"),
+		TaxID:   os.Getenv("INVOICE_COMPANY_TAX_ID"),
+		Email:   os.Getenv("INVOICE_COMPANY_EMAIL"),
+	}))
+	idempotencyService := idempotency.NewService(idempotencyRepo, config.DurationFromEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour))
 
 	subHandler := web.NewSubscriptionHandler(subService)
-	authHandler := web.NewAuthHandler(authService, jwtSecret)
+	authHandler := web.NewAuthHandler(authService)
+	planHandler := web.NewPlanHandler(planService)
+	adminHandler := web.NewAdminHandler(subService, authService)
+	invoiceHandler := web.NewInvoiceHandler(invoiceService)
+	couponHandler := web.NewCouponHandler(couponService)
+	healthHandler := web.NewHealthHandler(map[string]web.HealthCheck{
+		"postgres": pool.Ping,
+		"rabbitmq": publisher.Ping,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	router := web.SetupRouter(subHandler, authHandler, jwtSecret)
+	relay := outbox.NewRelay(outboxRepo, publisher, 100)
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Run(ctx, config.DurationFromEnv("OUTBOX_POLL_INTERVAL", time.Second))
+	}()
+
+	router := web.SetupRouter(subHandler, authHandler, planHandler, adminHandler, invoiceHandler, couponHandler, healthHandler, idempotencyService, jwtSecret, authService)
 
 	port := fmt.Sprintf(":%s", apiPort)
-	log.Printf("Server is running on port %s", port)
+	server := &http.Server{Addr: port, Handler: router}
+	go func() {
+		log.Printf("Server is running on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Could not start server: %s\n", err)
+		}
+	}()
+
+	// As métricas ficam numa porta interna, fora do router público e da autenticação da API.
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", promhttp.Handler())
+	metricsServer := &http.Server{Addr: fmt.Sprintf(":%d", config.IntFromEnv("METRICS_PORT", 9090)), Handler: metricsMux}
+	go func() {
+		log.Printf("Metrics server is running on %s", metricsServer.Addr)
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics server failed: %s", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server...")
 
-	if err := http.ListenAndServe(port, router); err != nil {
-		log.Fatalf("Could not start server: %s\n", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.DurationFromEnv("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+
+	// Shutdown deixa de aceitar conexões e espera que os pedidos em curso terminem.
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server did not shut down cleanly: %s", err)
+	}
+	select {
+	case <-relayDone:
+	case <-shutdownCtx.Done():
+		log.Println("Outbox relay did not stop before the shutdown deadline.")
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Metrics server did not shut down cleanly: %s", err)
 	}
+	log.Println("Server stopped.")
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/manuzokas/subscription-api/internal/adapters/database"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up              apply all pending migrations
+  down [steps]    revert the last N applied migrations (default 1)
+  status          list migrations and when they were applied
+  to <version>    migrate up or down to the given version (0 reverts everything)`
+
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	databaseUrl := os.Getenv("DATABASE_URL")
+	if databaseUrl == "" {
+		log.Fatal("DATABASE_URL must be set")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseUrl)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	migrator, err := database.NewMigrator(pool)
+	if err != nil {
+		log.Fatalf("Unable to load migrations: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		printMigrations("Applied", applied)
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps = mustAtoi(os.Args[2])
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		printMigrations("Reverted", reverted)
+
+	case "to":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		changed, err := migrator.To(ctx, mustAtoi(os.Args[2]))
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		printMigrations("Migrated", changed)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Could not read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, appliedAt)
+		}
+
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func printMigrations(verb string, migrations []database.Migration) {
+	if len(migrations) == 0 {
+		fmt.Println("Nothing to do, database is up to date.")
+		return
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+func mustAtoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		log.Fatalf("Invalid number %q: %v", s, err)
+	}
+	return n
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/adapters/messaging"
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func handleSubscriptionCreated(subService *subscription.Service) messaging.Handler {
+	return func(ctx context.Context, body []byte) error {
+		log.Printf("Received a message: %s", body)
+
+		var event subscription.SubscriptionCreatedEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return messaging.Permanent(fmt.Errorf("decoding message: %w", err))
+		}
+
+		sub, err := subService.StartSubscription(ctx, event.SubscriptionID, event.Plan, time.Now().UTC())
+		// Entregas repetidas (at-least-once) não devem reenviar o e-mail nem reiniciar o trial.
+		if errors.Is(err, subscription.ErrAlreadyStarted) {
+			log.Printf("Subscription %s already processed (status %s), skipping.", sub.ID, sub.Status)
+			return nil
+		}
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			return messaging.Permanent(fmt.Errorf("starting subscription %s: %w", event.SubscriptionID, err))
+		}
+		// Um conflito de versão (ex.: cancelamento concorrente) é retentado; na nova entrega
+		// a assinatura já não estará PENDING e será ignorada.
+		if err != nil {
+			return fmt.Errorf("starting subscription %s: %w", event.SubscriptionID, err)
+		}
+		log.Printf("Subscription %s status updated to %s.", sub.ID, sub.Status)
+
+		if sub.Status == domain.StatusCancelled {
+			return nil
+		}
+		// A assinatura já foi gravada: devolver a mensagem à fila não serviria de nada, porque a
+		// nova entrega pára em ErrAlreadyStarted. Interrompido pelo shutdown, o e-mail fica por
+		// enviar e a mensagem é confirmada.
+		log.Printf("Sending welcome email to %s for subscription %s...", event.Email, event.SubscriptionID)
+		select {
+		case <-time.After(3 * time.Second):
+			log.Println("Email sent!")
+		case <-ctx.Done():
+			log.Printf("Welcome email for subscription %s interrupted by shutdown, not sent: %s", event.SubscriptionID, ctx.Err())
+		}
+		return nil
+	}
+}
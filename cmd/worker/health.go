@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/manuzokas/subscription-api/internal/adapters/messaging"
+	"github.com/manuzokas/subscription-api/internal/adapters/web"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+type workerHealth struct {
+	Status   string                   `json:"status"`
+	Consumer messaging.ConsumerStatus `json:"consumer"`
+}
+
+// newHealthServer expõe as sondas do worker: /healthz reporta o estado do consumidor e a hora
+// da última mensagem processada; /readyz verifica o Postgres, a conexão com o RabbitMQ e se o
+// consumidor continua a receber entregas. /metrics serve as métricas do Prometheus.
+func newHealthServer(port int, consumer *messaging.RabbitMQConsumer, conn *amqp091.Connection, ch *amqp091.Channel, pingDB web.HealthCheck) *http.Server {
+	readiness := web.NewHealthHandler(map[string]web.HealthCheck{
+		"postgres": pingDB,
+		"rabbitmq": func(ctx context.Context) error {
+			if conn.IsClosed() || ch.IsClosed() {
+				return errors.New("rabbitmq connection is closed")
+			}
+			return nil
+		},
+		"consumer": func(ctx context.Context) error {
+			if state := consumer.Status().State; state != messaging.ConsumerConsuming {
+				return fmt.Errorf("consumer is %s", state)
+			}
+			return nil
+		},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(workerHealth{Status: "ok", Consumer: consumer.Status()})
+	})
+	mux.HandleFunc("GET /readyz", readiness.ReadinessHandler)
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	return &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+}
@@ -2,18 +2,33 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/manuzokas/subscription-api/internal/adapters/database"
+	"github.com/manuzokas/subscription-api/internal/adapters/gateway"
+	"github.com/manuzokas/subscription-api/internal/adapters/messaging"
+	"github.com/manuzokas/subscription-api/internal/config"
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+	"github.com/manuzokas/subscription-api/internal/core/outbox"
+	"github.com/manuzokas/subscription-api/internal/core/payment"
 	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/manuzokas/subscription-api/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rabbitmq/amqp091-go"
 )
 
+// consumerTag identifica o consumidor no canal para que possa ser cancelado no shutdown.
+const consumerTag = "subscription-worker"
+
 func main() {
 	log.Println("Starting Worker...")
 
@@ -39,30 +54,150 @@ func main() {
 	}
 	defer ch.Close()
 
+	publisher, err := messaging.NewRabbitMQPublisher(amqpURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to RabbitMQ: %v", err)
+	}
+	defer publisher.Close()
+
 	pool, err := pgxpool.New(context.Background(), databaseUrl)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v", err)
 	}
 	defer pool.Close()
+
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		migrator, err := database.NewMigrator(pool)
+		if err != nil {
+			log.Fatalf("Unable to load migrations: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Unable to run migrations: %v", err)
+		}
+		log.Println("Database migrations are up to date.")
+	}
+	prometheus.MustRegister(database.NewPoolCollector(pool))
+
 	subRepo := database.NewPostgresRepository(pool)
+	userRepo := database.NewPostgresUserRepository(pool)
+	planRepo := database.NewPostgresPlanRepository(pool)
+	couponRepo := database.NewPostgresCouponRepository(pool)
 
-	const queueName = "subscription_created_events"
-	q, err := ch.QueueDeclare(
-		queueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	idempotencyRepo := database.NewPostgresIdempotencyRepository(pool)
+	outboxRepo := database.NewPostgresOutboxRepository(pool)
+
+	paymentGateway, err := gateway.New(os.Getenv("PAYMENT_GATEWAY"))
+	if err != nil {
+		log.Fatalf("Unable to configure the payment gateway: %v", err)
+	}
+	paymentService := payment.NewService(paymentGateway, userRepo)
+	subService := subscription.NewService(subRepo, userRepo, planRepo, couponRepo, paymentService, subscription.BillingConfig{
+		TaxRateBasisPoints: config.IntFromEnv("INVOICE_TAX_RATE_BPS", 0),
+	})
+	idempotencyService := idempotency.NewService(idempotencyRepo, config.DurationFromEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour))
+
+	dunningSchedule := domain.DunningScheduleFromDays(config.IntListFromEnv("DUNNING_SCHEDULE", []int{1, 3, 7}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var jobs sync.WaitGroup
+	schedule := func(name string, interval time.Duration, job func(ctx context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			runPeriodically(ctx, name, interval, job)
+		}()
+	}
+
+	// Os jobs e o consumidor gravam eventos no outbox; o worker publica-os ele próprio para não
+	// depender de uma API em execução. O SKIP LOCKED permite vários relays em paralelo.
+	relay := outbox.NewRelay(outboxRepo, publisher, 100)
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		relay.Run(ctx, config.DurationFromEnv("OUTBOX_POLL_INTERVAL", time.Second))
+	}()
+
+	renewalInterval := config.DurationFromEnv("RENEWAL_INTERVAL", time.Minute)
+	schedule("renewal", renewalInterval, func(ctx context.Context) {
+		renewed, err := subService.RenewDueSubscriptions(ctx, time.Now().UTC(), dunningSchedule, 100)
+		if err != nil {
+			log.Printf("Error renewing subscriptions: %s", err)
+		}
+		if renewed > 0 {
+			log.Printf("Renewed %d subscriptions.", renewed)
+		}
+	})
+
+	trialCheckInterval := config.DurationFromEnv("TRIAL_CHECK_INTERVAL", time.Minute)
+	schedule("trial expiry", trialCheckInterval, func(ctx context.Context) {
+		now := time.Now().UTC()
+
+		reminded, err := subService.SendTrialEndingReminders(ctx, now, 3*24*time.Hour, 100)
+		if err != nil {
+			log.Printf("Error sending trial reminders: %s", err)
+		}
+		if reminded > 0 {
+			log.Printf("Sent %d trial ending reminders.", reminded)
+		}
+
+		ended, err := subService.ExpireTrials(ctx, now, 100)
+		if err != nil {
+			log.Printf("Error ending trials: %s", err)
+		}
+		if ended > 0 {
+			log.Printf("Ended %d trials.", ended)
+		}
+	})
+
+	schedule("dunning", config.DurationFromEnv("DUNNING_CHECK_INTERVAL", time.Minute), func(ctx context.Context) {
+		recovered, err := subService.RetryPastDueSubscriptions(ctx, time.Now().UTC(), dunningSchedule, 100)
+		if err != nil {
+			log.Printf("Error retrying past due subscriptions: %s", err)
+		}
+		if recovered > 0 {
+			log.Printf("Recovered %d past due subscriptions.", recovered)
+		}
+	})
+
+	schedule("scheduled resume", config.DurationFromEnv("PAUSE_CHECK_INTERVAL", time.Minute), func(ctx context.Context) {
+		resumed, err := subService.ResumeDueSubscriptions(ctx, time.Now().UTC(), 100)
+		if err != nil {
+			log.Printf("Error resuming paused subscriptions: %s", err)
+		}
+		if resumed > 0 {
+			log.Printf("Resumed %d paused subscriptions.", resumed)
+		}
+	})
+
+	schedule("idempotency key cleanup", config.DurationFromEnv("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour), func(ctx context.Context) {
+		purged, err := idempotencyService.PurgeExpired(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("Error purging idempotency keys: %s", err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d expired idempotency keys.", purged)
+		}
+	})
+
+	if err := ch.Qos(config.IntFromEnv("WORKER_PREFETCH", 10), 0, false); err != nil {
+		log.Fatalf("Failed to set QoS: %v", err)
+	}
+
+	retryPolicy := messaging.RetryPolicy{
+		MaxRetries: config.IntFromEnv("WORKER_MAX_RETRIES", 3),
+		BaseDelay:  config.DurationFromEnv("WORKER_RETRY_BASE_DELAY", 5*time.Second),
+	}
+	consumer, err := messaging.NewRabbitMQConsumer(ch, subscription.QueueSubscriptionCreated, retryPolicy, handleSubscriptionCreated(subService))
 	if err != nil {
-		log.Fatalf("Failed to declare a queue: %v", err)
+		log.Fatalf("Failed to declare queues: %v", err)
 	}
 
 	msgs, err := ch.Consume(
-		q.Name,
-		"",
-		true,
+		subscription.QueueSubscriptionCreated,
+		consumerTag,
+		false,
 		false,
 		false,
 		false,
@@ -72,41 +207,62 @@ func main() {
 		log.Fatalf("Failed to register a consumer: %v", err)
 	}
 
-	forever := make(chan struct{})
+	go consumer.Consume(msgs)
 
+	healthServer := newHealthServer(config.IntFromEnv("WORKER_HEALTH_PORT", 8081), consumer, conn, ch, pool.Ping)
 	go func() {
-		for d := range msgs {
-			log.Printf("Received a message: %s", d.Body)
-
-			var event subscription.SubscriptionCreatedEvent
-			if err := json.Unmarshal(d.Body, &event); err != nil {
-				log.Printf("Error decoding message: %s", err)
-				continue
-			}
-
-			log.Printf("Sending welcome email to %s for subscription %s...", event.Email, event.SubscriptionID)
-			time.Sleep(3 * time.Second)
-			log.Println("Email sent!")
-
-			sub, err := subRepo.FindByID(context.Background(), event.SubscriptionID)
-			if err != nil {
-				log.Printf("Error finding subscription %s: %s", event.SubscriptionID, err)
-				continue
-			}
-
-			now := time.Now().UTC()
-			sub.Status = "TRIAL"
-			sub.TrialEndsAt = &[]time.Time{now.Add(14 * 24 * time.Hour)}[0]
-			sub.UpdatedAt = now
-
-			if err := subRepo.Save(context.Background(), sub); err != nil {
-				log.Printf("Error updating subscription %s: %s", event.SubscriptionID, err)
-				continue
-			}
-			log.Printf("Subscription %s status updated to TRIAL.", sub.ID)
+		log.Printf("Health server is running on %s", healthServer.Addr)
+		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Health server failed: %s", err)
 		}
 	}()
 
 	log.Printf(" [*] Waiting for messages. To exit press CTRL+C")
-	<-forever
+	<-ctx.Done()
+	log.Println("Shutting down worker...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.DurationFromEnv("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+
+	// Primeiro o broker deixa de enviar entregas; depois esperamos pela mensagem em curso,
+	// pelos jobs periódicos e pelo relay antes de fechar o canal e as conexões (defers acima).
+	if err := ch.Cancel(consumerTag, false); err != nil {
+		log.Printf("Error cancelling consumer: %s", err)
+	}
+	if err := consumer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Consumer did not drain before the shutdown deadline: %s", err)
+	}
+
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-shutdownCtx.Done():
+		log.Println("Periodic jobs did not finish before the shutdown deadline.")
+	}
+	// O servidor de saúde fica de pé durante o drain para que as sondas vejam o estado DRAINING.
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Health server did not shut down cleanly: %s", err)
+	}
+	log.Println("Worker stopped.")
+}
+
+// runPeriodically executa job a cada interval até ctx ser cancelado. Uma execução em curso
+// não é interrompida pelo cancelamento: o job corre até ao fim com um contexto próprio.
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context)) {
+	log.Printf("Scheduling %s job every %s", name, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job(context.WithoutCancel(ctx))
+		}
+	}
 }
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("loading embedded migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, versions must be sequential", i, m.Version)
+		}
+	}
+}
+
+func TestLoadMigrationsSortsAndPairsFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"m/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"m/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"m/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"m/README.md":            {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Name != "second" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[0].Down != "DROP TABLE a;" {
+		t.Errorf("down migration not paired, got %q", migrations[0].Down)
+	}
+}
+
+func TestLoadMigrationsRejectsMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+	}
+	if _, err := loadMigrations(fsys, "m"); err == nil {
+		t.Fatal("expected error for migration without down file")
+	}
+}
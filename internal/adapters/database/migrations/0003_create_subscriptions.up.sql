@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    plan_id VARCHAR(255) NOT NULL REFERENCES plans(id),
+    status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    cancelled_at TIMESTAMPTZ,
+    trial_ends_at TIMESTAMPTZ,
+    current_period_start TIMESTAMPTZ,
+    current_period_end TIMESTAMPTZ,
+    payment_method_id VARCHAR(255),
+    trial_reminder_sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_created ON subscriptions (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status_period_end ON subscriptions (status, current_period_end);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status_trial_ends ON subscriptions (status, trial_ends_at);
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS pending_plan_id VARCHAR(255) REFERENCES plans(id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_subscriptions_status_resumes_at;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS resumes_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS paused_at;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_status_next_dunning;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS next_dunning_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS dunning_attempts;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS past_due_since;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS payment_customer_id VARCHAR(255);
@@ -0,0 +1,28 @@
+-- Contador com uma única linha: incrementá-lo dentro da transação que grava a fatura
+-- garante numeração sequencial sem lacunas (um rollback desfaz também o incremento).
+CREATE TABLE IF NOT EXISTS invoice_number_counter (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_number BIGINT NOT NULL
+);
+
+INSERT INTO invoice_number_counter (id, last_number) VALUES (TRUE, 0) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id VARCHAR(255) PRIMARY KEY,
+    number BIGINT UNIQUE NOT NULL,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    subscription_id VARCHAR(255) NOT NULL REFERENCES subscriptions(id),
+    reason VARCHAR(50) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    lines JSONB NOT NULL,
+    subtotal BIGINT NOT NULL,
+    tax BIGINT NOT NULL,
+    total BIGINT NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    charge_id VARCHAR(255),
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user_number ON invoices (user_id, number DESC);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id VARCHAR(255) PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    percent_off INTEGER NOT NULL DEFAULT 0,
+    amount_off BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3),
+    duration VARCHAR(50) NOT NULL,
+    duration_in_periods INTEGER NOT NULL DEFAULT 0,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    plan_ids TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- A restrição única impede que o mesmo utilizador resgate um cupom duas vezes.
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id VARCHAR(255) PRIMARY KEY,
+    coupon_id VARCHAR(255) NOT NULL REFERENCES coupons(id),
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    subscription_id VARCHAR(255) NOT NULL REFERENCES subscriptions(id),
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (coupon_id, user_id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS family VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Planos existentes formam, cada um, a sua própria família.
+UPDATE plans SET family = id WHERE family = '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_user_trial_family;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_plan_family;
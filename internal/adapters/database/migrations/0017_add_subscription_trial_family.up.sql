@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_plan_family VARCHAR(255);
+
+-- Os trials já concedidos contam para a família do plano em que foram iniciados.
+UPDATE subscriptions SET trial_plan_family = plan_id WHERE trial_ends_at IS NOT NULL AND trial_plan_family IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_trial_family ON subscriptions (user_id, trial_plan_family);
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS credit_balance BIGINT NOT NULL DEFAULT 0;
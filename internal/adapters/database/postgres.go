@@ -0,0 +1,13 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// executor é satisfeito tanto por *pgxpool.Pool como por pgx.Tx, permitindo
+// reutilizar as mesmas queries dentro e fora de uma transação.
+type executor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+const couponColumns = `id, code, percent_off, amount_off, currency, duration, duration_in_periods,
+		max_redemptions, times_redeemed, expires_at, plan_ids, active, created_at, updated_at`
+
+// uniqueViolation é o código SQLSTATE de uma violação de restrição única.
+const uniqueViolation = "23505"
+
+type PostgresCouponRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresCouponRepository(pool *pgxpool.Pool) *PostgresCouponRepository {
+	return &PostgresCouponRepository{pool: pool}
+}
+
+// Save insere ou atualiza os termos do cupom. O contador de resgates só é alterado por
+// redeemCoupon, para não perder incrementos concorrentes. Um código já usado por outro cupom
+// (ex: dois pedidos de criação em simultâneo) devolve domain.ErrCouponCodeTaken.
+func (r *PostgresCouponRepository) Save(ctx context.Context, c *domain.Coupon) error {
+	query := `
+		INSERT INTO coupons (` + couponColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			max_redemptions = EXCLUDED.max_redemptions,
+			expires_at = EXCLUDED.expires_at,
+			plan_ids = EXCLUDED.plan_ids,
+			active = EXCLUDED.active,
+			updated_at = EXCLUDED.updated_at;
+	`
+	var currency *string
+	if c.Currency != "" {
+		currency = &c.Currency
+	}
+	_, err := r.pool.Exec(ctx, query,
+		c.ID, c.Code, c.PercentOff, c.AmountOff, currency, c.Duration, c.DurationInPeriods,
+		c.MaxRedemptions, c.TimesRedeemed, c.ExpiresAt, c.PlanIDs, c.Active, c.CreatedAt, c.UpdatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return domain.ErrCouponCodeTaken
+	}
+	return err
+}
+
+func (r *PostgresCouponRepository) FindByID(ctx context.Context, id string) (*domain.Coupon, error) {
+	return r.findOne(ctx, "id = $1", id)
+}
+
+func (r *PostgresCouponRepository) FindByCode(ctx context.Context, code string) (*domain.Coupon, error) {
+	return r.findOne(ctx, "code = $1", code)
+}
+
+func (r *PostgresCouponRepository) findOne(ctx context.Context, condition string, arg any) (*domain.Coupon, error) {
+	query := `
+		SELECT ` + couponColumns + `
+		FROM coupons
+		WHERE ` + condition + `;
+	`
+	c, err := scanCoupon(r.pool.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *PostgresCouponRepository) List(ctx context.Context, includeInactive bool) ([]*domain.Coupon, error) {
+	query := `
+		SELECT ` + couponColumns + `
+		FROM coupons
+		WHERE active OR $1
+		ORDER BY created_at;
+	`
+	rows, err := r.pool.Query(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := []*domain.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, c)
+	}
+	return coupons, rows.Err()
+}
+
+func (r *PostgresCouponRepository) HasRedeemed(ctx context.Context, couponID, userID string) (bool, error) {
+	var redeemed bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2);
+	`, couponID, userID).Scan(&redeemed)
+	return redeemed, err
+}
+
+// redeemCoupon conta o resgate e regista-o. Tem de correr na mesma transação que grava a
+// assinatura; o UPDATE condicionado garante o limite de resgates mesmo com pedidos concorrentes.
+func redeemCoupon(ctx context.Context, tx pgx.Tx, redemption *domain.CouponRedemption) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE coupons SET times_redeemed = times_redeemed + 1
+		WHERE id = $1 AND (max_redemptions = 0 OR times_redeemed < max_redemptions);
+	`, redemption.CouponID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrCouponExhausted
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO coupon_redemptions (id, coupon_id, user_id, subscription_id, created_at)
+		VALUES ($1, $2, $3, $4, $5);
+	`, redemption.ID, redemption.CouponID, redemption.UserID, redemption.SubscriptionID, redemption.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return domain.ErrCouponAlreadyRedeemed
+	}
+	return err
+}
+
+// releaseRedemption apaga o resgate feito pela assinatura, se existir, e devolve-o ao contador
+// do cupom, para que o utilizador e o limite de resgates o possam voltar a usar.
+func releaseRedemption(ctx context.Context, tx pgx.Tx, subscriptionID string) error {
+	_, err := tx.Exec(ctx, `
+		WITH released AS (
+			DELETE FROM coupon_redemptions WHERE subscription_id = $1 RETURNING coupon_id
+		)
+		UPDATE coupons SET times_redeemed = times_redeemed - 1
+		WHERE id IN (SELECT coupon_id FROM released);
+	`, subscriptionID)
+	return err
+}
+
+func scanCoupon(row pgx.Row) (*domain.Coupon, error) {
+	var c domain.Coupon
+	var currency *string
+	err := row.Scan(
+		&c.ID, &c.Code, &c.PercentOff, &c.AmountOff, &currency, &c.Duration, &c.DurationInPeriods,
+		&c.MaxRedemptions, &c.TimesRedeemed, &c.ExpiresAt, &c.PlanIDs, &c.Active, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if currency != nil {
+		c.Currency = *currency
+	}
+	return &c, nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+)
+
+type PostgresIdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresIdempotencyRepository(pool *pgxpool.Pool) *PostgresIdempotencyRepository {
+	return &PostgresIdempotencyRepository{pool: pool}
+}
+
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, rec *idempotency.Record) (*idempotency.Record, error) {
+	// Chaves expiradas são reaproveitadas; chaves válidas nunca são sobrescritas.
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		RETURNING key;
+	`
+	var key string
+	err := r.pool.QueryRow(ctx, query, rec.UserID, rec.Key, rec.RequestHash, rec.CreatedAt, rec.ExpiresAt).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	existing := idempotency.Record{UserID: rec.UserID, Key: rec.Key}
+	var statusCode *int
+	var contentType *string
+	var headers, body []byte
+	err = r.pool.QueryRow(ctx, `
+		SELECT request_hash, status_code, content_type, response_headers, response_body, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2;
+	`, rec.UserID, rec.Key).Scan(
+		&existing.RequestHash, &statusCode, &contentType, &headers, &body, &existing.CreatedAt, &existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != nil {
+		existing.Response = &idempotency.StoredResponse{StatusCode: *statusCode, Body: body}
+		if contentType != nil {
+			existing.Response.ContentType = *contentType
+		}
+		if headers != nil {
+			if err := json.Unmarshal(headers, &existing.Response.Headers); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &existing, nil
+}
+
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, userID, key string, resp idempotency.StoredResponse) error {
+	headers, err := json.Marshal(resp.Headers)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_headers = $5, response_body = $6
+		WHERE user_id = $1 AND key = $2;
+	`
+	_, err = r.pool.Exec(ctx, query, userID, key, resp.StatusCode, resp.ContentType, headers, resp.Body)
+	return err
+}
+
+func (r *PostgresIdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL;`
+	_, err := r.pool.Exec(ctx, query, userID, key)
+	return err
+}
+
+func (r *PostgresIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1;`, now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/core/invoice"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+const invoiceColumns = `id, number, user_id, subscription_id, reason, currency, lines, subtotal, tax, total,
+		period_start, period_end, charge_id, paid_at, created_at`
+
+type PostgresInvoiceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresInvoiceRepository(pool *pgxpool.Pool) *PostgresInvoiceRepository {
+	return &PostgresInvoiceRepository{pool: pool}
+}
+
+// insertInvoice atribui o próximo número sequencial e grava a fatura. Tem de correr na mesma
+// transação que a alteração da assinatura; o lock na linha do contador serializa a numeração.
+func insertInvoice(ctx context.Context, tx pgx.Tx, inv *domain.Invoice) error {
+	var number int64
+	err := tx.QueryRow(ctx, `
+		UPDATE invoice_number_counter SET last_number = last_number + 1
+		RETURNING last_number;
+	`).Scan(&number)
+	if err != nil {
+		return err
+	}
+
+	lines, err := json.Marshal(inv.Lines)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO invoices (` + invoiceColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
+	`
+	_, err = tx.Exec(ctx, query,
+		inv.ID, number, inv.UserID, inv.SubscriptionID, inv.Reason, inv.Currency, lines,
+		inv.Subtotal, inv.Tax, inv.Total, inv.PeriodStart, inv.PeriodEnd, inv.ChargeID, inv.PaidAt, inv.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	inv.Number = domain.FormatInvoiceNumber(number)
+	return nil
+}
+
+func (r *PostgresInvoiceRepository) FindByID(ctx context.Context, id string) (*domain.Invoice, error) {
+	query := `
+		SELECT ` + invoiceColumns + `
+		FROM invoices
+		WHERE id = $1;
+	`
+	inv, err := scanInvoice(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *PostgresInvoiceRepository) ListByUser(ctx context.Context, userID string, filter invoice.ListFilter) ([]*domain.Invoice, error) {
+	query := `
+		SELECT ` + invoiceColumns + `
+		FROM invoices
+		WHERE user_id = $1 AND ($2 = '' OR subscription_id = $2)
+		ORDER BY number DESC;
+	`
+	rows, err := r.pool.Query(ctx, query, userID, filter.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*domain.Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+func scanInvoice(row pgx.Row) (*domain.Invoice, error) {
+	var inv domain.Invoice
+	var number int64
+	var lines []byte
+	err := row.Scan(
+		&inv.ID, &number, &inv.UserID, &inv.SubscriptionID, &inv.Reason, &inv.Currency, &lines,
+		&inv.Subtotal, &inv.Tax, &inv.Total, &inv.PeriodStart, &inv.PeriodEnd, &inv.ChargeID, &inv.PaidAt, &inv.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(lines, &inv.Lines); err != nil {
+		return nil, err
+	}
+	inv.Number = domain.FormatInvoiceNumber(number)
+	return &inv, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// ClaimPending reserva as mensagens num único UPDATE; o SKIP LOCKED deixa vários relays
+// reservarem lotes diferentes em paralelo.
+func (r *PostgresOutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	query := `
+		UPDATE outbox SET claimed_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE sent_at IS NULL AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, queue, payload, created_at, attempts;
+	`
+	rows, err := r.pool.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.OutboxMessage, error) {
+		var msg domain.OutboxMessage
+		err := row.Scan(&msg.ID, &msg.Queue, &msg.Payload, &msg.CreatedAt, &msg.Attempts)
+		return &msg, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// O RETURNING não garante a ordem do SELECT interno.
+	slices.SortFunc(msgs, func(a, b *domain.OutboxMessage) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return msgs, nil
+}
+
+func (r *PostgresOutboxRepository) MarkSent(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, last_error = NULL, sent_at = NOW(), claimed_until = NULL
+		WHERE id = $1;
+	`, id)
+	return err
+}
+
+func (r *PostgresOutboxRepository) MarkFailed(ctx context.Context, id string, cause string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, last_error = $2, claimed_until = NULL
+		WHERE id = $1;
+	`, id, cause)
+	return err
+}
+
+func insertOutboxMessages(ctx context.Context, db executor, msgs []*domain.OutboxMessage) error {
+	query := `
+		INSERT INTO outbox (id, queue, payload, created_at)
+		VALUES ($1, $2, $3, $4);
+	`
+	for _, msg := range msgs {
+		if _, err := db.Exec(ctx, query, msg.ID, msg.Queue, msg.Payload, msg.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
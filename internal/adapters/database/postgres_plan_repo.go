@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type PostgresPlanRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresPlanRepository(pool *pgxpool.Pool) *PostgresPlanRepository {
+	return &PostgresPlanRepository{pool: pool}
+}
+
+func (r *PostgresPlanRepository) Save(ctx context.Context, p *domain.Plan) error {
+	query := `
+		INSERT INTO plans (id, name, price, currency, billing_interval, interval_days, trial_days, active, created_at, updated_at, family)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			price = EXCLUDED.price,
+			currency = EXCLUDED.currency,
+			billing_interval = EXCLUDED.billing_interval,
+			interval_days = EXCLUDED.interval_days,
+			trial_days = EXCLUDED.trial_days,
+			active = EXCLUDED.active,
+			updated_at = EXCLUDED.updated_at,
+			family = EXCLUDED.family;
+	`
+	_, err := r.pool.Exec(ctx, query,
+		p.ID, p.Name, p.Price, p.Currency, p.Interval, p.IntervalDays,
+		p.TrialDays, p.Active, p.CreatedAt, p.UpdatedAt, p.Family,
+	)
+	return err
+}
+
+func (r *PostgresPlanRepository) FindByID(ctx context.Context, id string) (*domain.Plan, error) {
+	query := `
+		SELECT id, name, price, currency, billing_interval, interval_days, trial_days, active, created_at, updated_at, family
+		FROM plans
+		WHERE id = $1;
+	`
+	var p domain.Plan
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.Name, &p.Price, &p.Currency, &p.Interval, &p.IntervalDays,
+		&p.TrialDays, &p.Active, &p.CreatedAt, &p.UpdatedAt, &p.Family,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PostgresPlanRepository) List(ctx context.Context, includeInactive bool) ([]*domain.Plan, error) {
+	query := `
+		SELECT id, name, price, currency, billing_interval, interval_days, trial_days, active, created_at, updated_at, family
+		FROM plans
+		WHERE active OR $1
+		ORDER BY created_at;
+	`
+	rows, err := r.pool.Query(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []*domain.Plan{}
+	for rows.Next() {
+		var p domain.Plan
+		if err := rows.Scan(
+			&p.ID, &p.Name, &p.Price, &p.Currency, &p.Interval, &p.IntervalDays,
+			&p.TrialDays, &p.Active, &p.CreatedAt, &p.UpdatedAt, &p.Family,
+		); err != nil {
+			return nil, err
+		}
+		plans = append(plans, &p)
+	}
+	return plans, rows.Err()
+}
@@ -2,13 +2,22 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
 	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
+const subscriptionColumns = `id, user_id, plan_id, status, created_at, updated_at, cancelled_at, trial_ends_at,
+		current_period_start, current_period_end, payment_method_id, trial_reminder_sent_at, pending_plan_id,
+		paused_at, resumes_at, cancel_at_period_end, past_due_since, dunning_attempts, next_dunning_at, discount, trial_plan_family, credit_balance, version`
+
 type PostgresRepository struct {
 	pool *pgxpool.Pool
 }
@@ -19,37 +28,165 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	}
 }
 
+// Save grava a assinatura com controlo de concorrência otimista: a atualização só é aplicada
+// se a versão guardada ainda for sub.Version, caso contrário devolve *domain.VersionConflictError.
 func (r *PostgresRepository) Save(ctx context.Context, sub *domain.Subscription) error {
+	version, err := saveSubscription(ctx, r.pool, sub)
+	if err != nil {
+		return err
+	}
+	sub.Version = version
+	return nil
+}
+
+// SaveWithEvents grava a assinatura e as mensagens de outbox na mesma transação.
+func (r *PostgresRepository) SaveWithEvents(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error {
+	var version int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if version, err = saveSubscription(ctx, tx, sub); err != nil {
+			return err
+		}
+		return insertOutboxMessages(ctx, tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	sub.Version = version
+	return nil
+}
+
+// SaveWithInvoice grava a assinatura, a fatura e as mensagens de outbox na mesma transação.
+func (r *PostgresRepository) SaveWithInvoice(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice, events ...*domain.OutboxMessage) error {
+	var version int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if version, err = saveSubscription(ctx, tx, sub); err != nil {
+			return err
+		}
+		if err := insertInvoice(ctx, tx, inv); err != nil {
+			return err
+		}
+		return insertOutboxMessages(ctx, tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	sub.Version = version
+	return nil
+}
+
+// SaveWithRedemption grava a nova assinatura, o resgate do cupom e as mensagens de outbox na mesma transação.
+func (r *PostgresRepository) SaveWithRedemption(ctx context.Context, sub *domain.Subscription, redemption *domain.CouponRedemption, events ...*domain.OutboxMessage) error {
+	var version int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if version, err = saveSubscription(ctx, tx, sub); err != nil {
+			return err
+		}
+		if err := redeemCoupon(ctx, tx, redemption); err != nil {
+			return err
+		}
+		return insertOutboxMessages(ctx, tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	sub.Version = version
+	return nil
+}
+
+// SaveReleasingRedemption grava a assinatura e desfaz o resgate de cupom dela na mesma transação.
+func (r *PostgresRepository) SaveReleasingRedemption(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error {
+	var version int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if version, err = saveSubscription(ctx, tx, sub); err != nil {
+			return err
+		}
+		if err := releaseRedemption(ctx, tx, sub.ID); err != nil {
+			return err
+		}
+		return insertOutboxMessages(ctx, tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	sub.Version = version
+	return nil
+}
+
+// saveSubscription insere a assinatura quando ainda não foi persistida (Version zero) ou
+// atualiza-a condicionada à versão lida, devolvendo a nova versão.
+func saveSubscription(ctx context.Context, db executor, sub *domain.Subscription) (int, error) {
+	discount, err := marshalDiscount(sub.Discount)
+	if err != nil {
+		return 0, err
+	}
+
+	if sub.Version == 0 {
+		query := `
+			INSERT INTO subscriptions (` + subscriptionColumns + `)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, 1);
+		`
+		_, err := db.Exec(ctx, query,
+			sub.ID, sub.UserID, sub.PlanID, sub.Status,
+			sub.CreatedAt, sub.UpdatedAt, sub.CancelledAt, sub.TrialEndsAt,
+			sub.CurrentPeriodStart, sub.CurrentPeriodEnd, sub.PaymentMethodID, sub.TrialReminderSentAt,
+			sub.PendingPlanID, sub.PausedAt, sub.ResumesAt, sub.CancelAtPeriodEnd,
+			sub.PastDueSince, sub.DunningAttempts, sub.NextDunningAt, discount, sub.TrialPlanFamily, sub.CreditBalance,
+		)
+		return 1, err
+	}
+
 	query := `
-		INSERT INTO subscriptions (id, user_id, plan_id, status, created_at, updated_at, cancelled_at, trial_ends_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (id) DO UPDATE SET
-			user_id = EXCLUDED.user_id,
-			plan_id = EXCLUDED.plan_id,
-			status = EXCLUDED.status,
-			updated_at = EXCLUDED.updated_at,
-			cancelled_at = EXCLUDED.cancelled_at,
-			trial_ends_at = EXCLUDED.trial_ends_at;
-	`
-	_, err := r.pool.Exec(ctx, query,
+		UPDATE subscriptions SET
+			user_id = $2,
+			plan_id = $3,
+			status = $4,
+			updated_at = $5,
+			cancelled_at = $6,
+			trial_ends_at = $7,
+			current_period_start = $8,
+			current_period_end = $9,
+			payment_method_id = $10,
+			trial_reminder_sent_at = $11,
+			pending_plan_id = $12,
+			paused_at = $13,
+			resumes_at = $14,
+			cancel_at_period_end = $15,
+			past_due_since = $16,
+			dunning_attempts = $17,
+			next_dunning_at = $18,
+			discount = $19,
+			trial_plan_family = $20,
+			credit_balance = $21,
+			version = version + 1
+		WHERE id = $1 AND version = $22;
+	`
+	tag, err := db.Exec(ctx, query,
 		sub.ID, sub.UserID, sub.PlanID, sub.Status,
-		sub.CreatedAt, sub.UpdatedAt, sub.CancelledAt, sub.TrialEndsAt,
+		sub.UpdatedAt, sub.CancelledAt, sub.TrialEndsAt,
+		sub.CurrentPeriodStart, sub.CurrentPeriodEnd, sub.PaymentMethodID, sub.TrialReminderSentAt,
+		sub.PendingPlanID, sub.PausedAt, sub.ResumesAt, sub.CancelAtPeriodEnd,
+		sub.PastDueSince, sub.DunningAttempts, sub.NextDunningAt, discount, sub.TrialPlanFamily, sub.CreditBalance, sub.Version,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, &domain.VersionConflictError{SubscriptionID: sub.ID, Version: sub.Version}
+	}
+	return sub.Version + 1, nil
 }
 
 func (r *PostgresRepository) FindByID(ctx context.Context, id string) (*domain.Subscription, error) {
 	query := `
-		SELECT id, user_id, plan_id, status, created_at, updated_at, cancelled_at, trial_ends_at
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
 		WHERE id = $1;
 	`
-	var sub domain.Subscription
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&sub.ID, &sub.UserID, &sub.PlanID, &sub.Status,
-		&sub.CreatedAt, &sub.UpdatedAt, &sub.CancelledAt, &sub.TrialEndsAt,
-	)
-
+	sub, err := scanSubscription(r.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrSubscriptionNotFound
@@ -57,5 +194,207 @@ func (r *PostgresRepository) FindByID(ctx context.Context, id string) (*domain.S
 		return nil, err
 	}
 
+	return sub, nil
+}
+
+func (r *PostgresRepository) ListByUser(ctx context.Context, userID string, filter subscription.ListFilter) ([]*domain.Subscription, error) {
+	return r.list(ctx, userID, filter)
+}
+
+func (r *PostgresRepository) ListAll(ctx context.Context, filter subscription.ListFilter) ([]*domain.Subscription, error) {
+	return r.list(ctx, "", filter)
+}
+
+// list monta a query de listagem com paginação por keyset sobre (created_at, id).
+// Um userID vazio lista as assinaturas de todos os utilizadores.
+func (r *PostgresRepository) list(ctx context.Context, userID string, filter subscription.ListFilter) ([]*domain.Subscription, error) {
+	var conditions []string
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if userID != "" {
+		conditions = append(conditions, "user_id = "+addArg(userID))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+addArg(filter.Status))
+	}
+	if filter.PlanID != "" {
+		conditions = append(conditions, "plan_id = "+addArg(filter.PlanID))
+	}
+
+	order, comparison := "ASC", ">"
+	if filter.Descending {
+		order, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)",
+			comparison, addArg(filter.After.CreatedAt), addArg(filter.After.ID)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		` + where + `
+		ORDER BY created_at ` + order + `, id ` + order + `
+		LIMIT ` + addArg(filter.Limit) + `;
+	`
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) FindDueForRenewal(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = $1 AND current_period_end <= $2
+		ORDER BY current_period_end
+		LIMIT $3;
+	`
+	rows, err := r.pool.Query(ctx, query, domain.StatusActive, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) FindTrialsEndedBefore(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = $1 AND trial_ends_at <= $2
+		ORDER BY trial_ends_at
+		LIMIT $3;
+	`
+	rows, err := r.pool.Query(ctx, query, domain.StatusTrial, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) FindTrialsEndingBetween(ctx context.Context, from, to time.Time, limit int) ([]*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = $1 AND trial_ends_at > $2 AND trial_ends_at <= $3 AND trial_reminder_sent_at IS NULL
+		ORDER BY trial_ends_at
+		LIMIT $4;
+	`
+	rows, err := r.pool.Query(ctx, query, domain.StatusTrial, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) FindPausedDueForResume(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = $1 AND resumes_at <= $2
+		ORDER BY resumes_at
+		LIMIT $3;
+	`
+	rows, err := r.pool.Query(ctx, query, domain.StatusPaused, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) FindDunningDue(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = $1 AND next_dunning_at <= $2
+		ORDER BY next_dunning_at
+		LIMIT $3;
+	`
+	rows, err := r.pool.Query(ctx, query, domain.StatusPastDue, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSubscriptions(rows)
+}
+
+func (r *PostgresRepository) HasHadTrial(ctx context.Context, userID, planFamily string) (bool, error) {
+	var hadTrial bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM subscriptions WHERE user_id = $1 AND trial_plan_family = $2);
+	`, userID, planFamily).Scan(&hadTrial)
+	return hadTrial, err
+}
+
+// CountByStatus conta as assinaturas em cada estado; estados sem assinaturas não aparecem no mapa.
+func (r *PostgresRepository) CountByStatus(ctx context.Context) (map[domain.Status]int, error) {
+	rows, err := r.pool.Query(ctx, `SELECT status, COUNT(*) FROM subscriptions GROUP BY status;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.Status]int)
+	for rows.Next() {
+		var status domain.Status
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+func scanSubscription(row pgx.Row) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	var discount []byte
+	err := row.Scan(
+		&sub.ID, &sub.UserID, &sub.PlanID, &sub.Status,
+		&sub.CreatedAt, &sub.UpdatedAt, &sub.CancelledAt, &sub.TrialEndsAt,
+		&sub.CurrentPeriodStart, &sub.CurrentPeriodEnd, &sub.PaymentMethodID, &sub.TrialReminderSentAt,
+		&sub.PendingPlanID, &sub.PausedAt, &sub.ResumesAt, &sub.CancelAtPeriodEnd,
+		&sub.PastDueSince, &sub.DunningAttempts, &sub.NextDunningAt, &discount, &sub.TrialPlanFamily, &sub.CreditBalance, &sub.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if discount != nil {
+		if err := json.Unmarshal(discount, &sub.Discount); err != nil {
+			return nil, err
+		}
+	}
 	return &sub, nil
 }
+
+// marshalDiscount guarda o desconto como JSONB; sem desconto a coluna fica NULL.
+func marshalDiscount(d *domain.Discount) ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func collectSubscriptions(rows pgx.Rows) ([]*domain.Subscription, error) {
+	defer rows.Close()
+
+	subs := []*domain.Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
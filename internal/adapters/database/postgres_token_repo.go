@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type PostgresTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresTokenRepository(pool *pgxpool.Pool) *PostgresTokenRepository {
+	return &PostgresTokenRepository{pool: pool}
+}
+
+func (r *PostgresTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	return insertRefreshToken(ctx, r.pool, token)
+}
+
+func (r *PostgresTokenRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by_id
+		FROM refresh_tokens WHERE token_hash = $1;
+	`
+	var t domain.RefreshToken
+	err := r.pool.QueryRow(ctx, query, hash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash,
+		&t.ExpiresAt, &t.CreatedAt, &t.RevokedAt, &t.ReplacedByID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PostgresTokenRepository) RotateRefreshToken(ctx context.Context, oldID string, next *domain.RefreshToken) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := insertRefreshToken(ctx, tx, next); err != nil {
+			return err
+		}
+
+		// A condição revoked_at IS NULL garante que dois pedidos concorrentes com o mesmo
+		// token não conseguem ambos fazer a rotação.
+		tag, err := tx.Exec(ctx, `
+			UPDATE refresh_tokens SET revoked_at = $2, replaced_by_id = $3
+			WHERE id = $1 AND revoked_at IS NULL;
+		`, oldID, next.CreatedAt, next.ID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrRefreshTokenReused
+		}
+		return nil
+	})
+}
+
+func (r *PostgresTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL;
+	`
+	_, err := r.pool.Exec(ctx, query, familyID)
+	return err
+}
+
+func (r *PostgresTokenRepository) IsFamilyActive(ctx context.Context, familyID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		);
+	`
+	var active bool
+	err := r.pool.QueryRow(ctx, query, familyID).Scan(&active)
+	return active, err
+}
+
+func insertRefreshToken(ctx context.Context, db executor, token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	_, err := db.Exec(ctx, query, token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	return err
+}
@@ -19,23 +19,23 @@ func NewPostgresUserRepository(pool *pgxpool.Pool) *PostgresUserRepository {
 
 func (r *PostgresUserRepository) CreateUser(ctx context.Context, user *domain.User) error {
 	query := `
-		INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6);
+		INSERT INTO users (id, name, email, password_hash, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
 	`
-	_, err := r.pool.Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
+	_, err := r.pool.Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
 func (r *PostgresUserRepository) FindUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
+		SELECT id, name, email, password_hash, role, payment_customer_id, created_at, updated_at
 		FROM users WHERE email = $1;
 	`
 	var user domain.User
-	err := r.pool.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	err := r.pool.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.PaymentCustomerID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -44,16 +44,31 @@ func (r *PostgresUserRepository) FindUserByEmail(ctx context.Context, email stri
 
 func (r *PostgresUserRepository) FindUserByID(ctx context.Context, id string) (*domain.User, error) {
 	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
+		SELECT id, name, email, password_hash, role, payment_customer_id, created_at, updated_at
 		FROM users WHERE id = $1;
 	`
 	var user domain.User
-	err := r.pool.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	err := r.pool.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.PaymentCustomerID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
+
+func (r *PostgresUserRepository) SetPaymentCustomerID(ctx context.Context, userID, customerID string) error {
+	query := `
+		UPDATE users SET payment_customer_id = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+	tag, err := r.pool.Exec(ctx, query, userID, customerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+)
+
+// Outcome é o resultado que o FakeGateway devolve numa cobrança.
+type Outcome int
+
+const (
+	OutcomeSucceed Outcome = iota
+	OutcomeDecline
+	OutcomeTimeout
+)
+
+// Meios de pagamento com comportamento fixo, úteis para testar o fluxo manualmente.
+const (
+	PaymentMethodDeclined = "pm_card_declined"
+	PaymentMethodTimeout  = "pm_timeout"
+)
+
+var errCustomerNotFound = errors.New("customer not found")
+
+// FakeGateway é um provedor de pagamentos em memória e determinístico. Por omissão aprova
+// todas as cobranças; Script enfileira resultados para as próximas cobranças e os meios de
+// pagamento PaymentMethodDeclined e PaymentMethodTimeout falham sempre.
+//
+// Os IDs de cliente derivam do ID do utilizador, para que processos distintos (API e worker)
+// com instâncias próprias do fake concordem entre si.
+type FakeGateway struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	script    []Outcome
+	customers map[string]map[string]bool
+	charges   []*payment.Charge
+	byKey     map[string]*payment.Charge
+	refunded  map[string]int64
+	refunds   int
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		now:       func() time.Time { return time.Now().UTC() },
+		customers: make(map[string]map[string]bool),
+		byKey:     make(map[string]*payment.Charge),
+		refunded:  make(map[string]int64),
+	}
+}
+
+// Script define os resultados das próximas cobranças, por ordem.
+func (g *FakeGateway) Script(outcomes ...Outcome) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.script = append(g.script, outcomes...)
+}
+
+// Charges devolve as cobranças aprovadas até agora.
+func (g *FakeGateway) Charges() []payment.Charge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	charges := make([]payment.Charge, 0, len(g.charges))
+	for _, c := range g.charges {
+		charges = append(charges, *c)
+	}
+	return charges
+}
+
+// Refunded devolve o valor já reembolsado da cobrança chargeID.
+func (g *FakeGateway) Refunded(chargeID string) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.refunded[chargeID]
+}
+
+func (g *FakeGateway) CreateCustomer(ctx context.Context, req payment.CustomerRequest) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := "cus_" + req.UserID
+	if _, ok := g.customers[id]; !ok {
+		g.customers[id] = make(map[string]bool)
+	}
+	return id, nil
+}
+
+// AttachPaymentMethod aceita qualquer cliente com um ID gerado por CreateCustomer, mesmo que
+// tenha sido criado por outro processo ou antes de um reinício.
+func (g *FakeGateway) AttachPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !strings.HasPrefix(customerID, "cus_") || customerID == "cus_" {
+		return fmt.Errorf("%w: %s", errCustomerNotFound, customerID)
+	}
+	methods, ok := g.customers[customerID]
+	if !ok {
+		methods = make(map[string]bool)
+		g.customers[customerID] = methods
+	}
+	methods[paymentMethodID] = true
+	return nil
+}
+
+func (g *FakeGateway) Charge(ctx context.Context, req payment.ChargeRequest) (*payment.Charge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", payment.ErrGatewayUnavailable, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if req.IdempotencyKey != "" {
+		if existing, ok := g.byKey[req.IdempotencyKey]; ok {
+			charge := *existing
+			return &charge, nil
+		}
+	}
+
+	switch g.nextOutcome(req.PaymentMethodID) {
+	case OutcomeDecline:
+		return nil, &payment.DeclinedError{Code: "card_declined"}
+	case OutcomeTimeout:
+		return nil, fmt.Errorf("%w: request timed out", payment.ErrGatewayUnavailable)
+	}
+
+	charge := &payment.Charge{
+		ID:              fmt.Sprintf("ch_%d", len(g.charges)+1),
+		CustomerID:      req.CustomerID,
+		PaymentMethodID: req.PaymentMethodID,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Description:     req.Description,
+		CreatedAt:       g.now(),
+	}
+	g.charges = append(g.charges, charge)
+	if req.IdempotencyKey != "" {
+		g.byKey[req.IdempotencyKey] = charge
+	}
+	result := *charge
+	return &result, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, req payment.RefundRequest) (*payment.Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var charge *payment.Charge
+	for _, c := range g.charges {
+		if c.ID == req.ChargeID {
+			charge = c
+			break
+		}
+	}
+	if charge == nil {
+		return nil, payment.ErrChargeNotFound
+	}
+
+	refundable := charge.Amount - g.refunded[charge.ID]
+	amount := req.Amount
+	if amount == 0 {
+		amount = refundable
+	}
+	if amount <= 0 || amount > refundable {
+		return nil, payment.ErrRefundExceedsCharge
+	}
+
+	g.refunded[charge.ID] += amount
+	g.refunds++
+	return &payment.Refund{
+		ID:        fmt.Sprintf("re_%d", g.refunds),
+		ChargeID:  charge.ID,
+		Amount:    amount,
+		CreatedAt: g.now(),
+	}, nil
+}
+
+// nextOutcome consome o próximo resultado do script ou, sem script, decide pelo meio de pagamento.
+func (g *FakeGateway) nextOutcome(paymentMethodID string) Outcome {
+	if len(g.script) > 0 {
+		outcome := g.script[0]
+		g.script = g.script[1:]
+		return outcome
+	}
+	switch paymentMethodID {
+	case PaymentMethodDeclined:
+		return OutcomeDecline
+	case PaymentMethodTimeout:
+		return OutcomeTimeout
+	default:
+		return OutcomeSucceed
+	}
+}
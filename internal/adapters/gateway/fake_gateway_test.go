@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func TestFakeGatewayScriptedOutcomes(t *testing.T) {
+	ctx := context.Background()
+	g := NewFakeGateway()
+	g.Script(OutcomeDecline, OutcomeTimeout)
+
+	req := payment.ChargeRequest{CustomerID: "cus_1", PaymentMethodID: "pm_ok", Amount: 1000, Currency: "BRL"}
+
+	if _, err := g.Charge(ctx, req); !errors.Is(err, domain.ErrPaymentFailed) {
+		t.Fatalf("first charge: expected decline, got %v", err)
+	}
+	if _, err := g.Charge(ctx, req); !errors.Is(err, payment.ErrGatewayUnavailable) || errors.Is(err, domain.ErrPaymentFailed) {
+		t.Fatalf("second charge: expected transient timeout, got %v", err)
+	}
+	charge, err := g.Charge(ctx, req)
+	if err != nil {
+		t.Fatalf("third charge: unexpected error %v", err)
+	}
+	if charge.Amount != 1000 || len(g.Charges()) != 1 {
+		t.Errorf("got charge %+v, %d charges recorded", charge, len(g.Charges()))
+	}
+}
+
+func TestFakeGatewayMagicPaymentMethods(t *testing.T) {
+	ctx := context.Background()
+	g := NewFakeGateway()
+
+	if _, err := g.Charge(ctx, payment.ChargeRequest{PaymentMethodID: PaymentMethodDeclined, Amount: 100}); !errors.Is(err, domain.ErrPaymentFailed) {
+		t.Errorf("expected decline for %s, got %v", PaymentMethodDeclined, err)
+	}
+	if _, err := g.Charge(ctx, payment.ChargeRequest{PaymentMethodID: PaymentMethodTimeout, Amount: 100}); !errors.Is(err, payment.ErrGatewayUnavailable) {
+		t.Errorf("expected timeout for %s, got %v", PaymentMethodTimeout, err)
+	}
+}
+
+func TestFakeGatewayIdempotentCharges(t *testing.T) {
+	ctx := context.Background()
+	g := NewFakeGateway()
+	req := payment.ChargeRequest{PaymentMethodID: "pm_ok", Amount: 500, IdempotencyKey: "invoice:inv-1"}
+
+	first, err := g.Charge(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := g.Charge(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.ID != second.ID || len(g.Charges()) != 1 {
+		t.Errorf("expected a single charge, got %s and %s (%d recorded)", first.ID, second.ID, len(g.Charges()))
+	}
+}
+
+func TestFakeGatewayRefunds(t *testing.T) {
+	ctx := context.Background()
+	g := NewFakeGateway()
+
+	charge, err := g.Charge(ctx, payment.ChargeRequest{PaymentMethodID: "pm_ok", Amount: 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := g.Refund(ctx, payment.RefundRequest{ChargeID: charge.ID, Amount: 400}); err != nil {
+		t.Fatalf("partial refund: unexpected error %v", err)
+	}
+	if _, err := g.Refund(ctx, payment.RefundRequest{ChargeID: charge.ID, Amount: 700}); !errors.Is(err, payment.ErrRefundExceedsCharge) {
+		t.Errorf("expected ErrRefundExceedsCharge, got %v", err)
+	}
+	refund, err := g.Refund(ctx, payment.RefundRequest{ChargeID: charge.ID})
+	if err != nil || refund.Amount != 600 {
+		t.Errorf("full refund of the remainder: got %+v, %v", refund, err)
+	}
+	if _, err := g.Refund(ctx, payment.RefundRequest{ChargeID: "ch_missing"}); !errors.Is(err, payment.ErrChargeNotFound) {
+		t.Errorf("expected ErrChargeNotFound, got %v", err)
+	}
+}
+
+func TestFakeGatewayAttachesToCustomersCreatedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	worker := NewFakeGateway()
+	customerID, err := worker.CreateCustomer(ctx, payment.CustomerRequest{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A API tem a sua própria instância, sem memória do cliente persistido pelo worker.
+	api := NewFakeGateway()
+	if err := api.AttachPaymentMethod(ctx, customerID, "pm_ok"); err != nil {
+		t.Errorf("attaching to a persisted customer: %v", err)
+	}
+	if err := api.AttachPaymentMethod(ctx, "unknown", "pm_ok"); !errors.Is(err, errCustomerNotFound) {
+		t.Errorf("expected errCustomerNotFound for an id not issued by the gateway, got %v", err)
+	}
+}
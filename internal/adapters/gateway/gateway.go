@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+)
+
+// New devolve o provedor de pagamentos indicado por provider (PAYMENT_GATEWAY).
+// Por enquanto só existe o provedor local "fake", usado também quando nada é configurado.
+func New(provider string) (payment.PaymentGateway, error) {
+	switch provider {
+	case "", "fake":
+		return NewFakeGateway(), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment gateway %q", provider)
+	}
+}
@@ -0,0 +1,57 @@
+package messaging
+
+import "time"
+
+// ConsumerState descreve a fase do ciclo de vida de um RabbitMQConsumer.
+type ConsumerState string
+
+const (
+	ConsumerIdle      ConsumerState = "IDLE"
+	ConsumerConsuming ConsumerState = "CONSUMING"
+	ConsumerDraining  ConsumerState = "DRAINING"
+	ConsumerStopped   ConsumerState = "STOPPED"
+)
+
+// ConsumerStatus é uma fotografia do consumidor, usada pelas sondas de saúde do worker.
+// Processed conta as mensagens tratadas, com sucesso ou não.
+type ConsumerStatus struct {
+	Queue         string        `json:"queue"`
+	State         ConsumerState `json:"state"`
+	InFlight      bool          `json:"inFlight"`
+	Processed     int64         `json:"processed"`
+	LastMessageAt *time.Time    `json:"lastMessageAt,omitempty"`
+}
+
+func (c *RabbitMQConsumer) Status() ConsumerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.status
+	if status.LastMessageAt != nil {
+		last := *status.LastMessageAt
+		status.LastMessageAt = &last
+	}
+	return status
+}
+
+func (c *RabbitMQConsumer) setState(state ConsumerState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Depois de parado o consumidor não volta a nenhum outro estado.
+	if c.status.State != ConsumerStopped {
+		c.status.State = state
+	}
+}
+
+func (c *RabbitMQConsumer) messageStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.InFlight = true
+}
+
+func (c *RabbitMQConsumer) messageFinished(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.InFlight = false
+	c.status.Processed++
+	c.status.LastMessageAt = &at
+}
@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "subscription_api"
+
+var (
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_published_total",
+		Help:      "Messages published and confirmed by the broker.",
+	}, []string{"queue"})
+
+	messagesPublishFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_publish_failures_total",
+		Help:      "Messages that could not be published or were not confirmed by the broker.",
+	}, []string{"queue"})
+
+	messagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_consumed_total",
+		Help:      "Deliveries handed to the consumer handler.",
+	}, []string{"queue"})
+
+	// action indica o destino da mensagem que falhou: retry, dead_letter ou requeue.
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_failed_total",
+		Help:      "Deliveries whose handler returned an error, by what was done with them.",
+	}, []string{"queue", "action"})
+)
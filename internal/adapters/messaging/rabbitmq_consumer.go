@@ -0,0 +1,317 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	headerRetryCount    = "x-retry-count"
+	headerLastError     = "x-last-error"
+	headerFailureReason = "x-failure-reason"
+	headerOriginalQueue = "x-original-queue"
+	headerFailedAt      = "x-failed-at"
+)
+
+// Handler processa o corpo de uma mensagem. Um erro provoca uma nova tentativa,
+// a menos que seja marcado com Permanent. Um handler interrompido pelo shutdown depois de
+// gravar efeitos que uma nova entrega não repetiria deve devolver nil, para a mensagem ser
+// confirmada em vez de voltar à fila.
+type Handler func(ctx context.Context, body []byte) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marca um erro que não vale a pena tentar de novo (ex: mensagem mal formada);
+// a mensagem é enviada diretamente para a dead-letter queue.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// RetryPolicy define quantas vezes uma mensagem é reprocessada e o atraso base entre tentativas.
+// O atraso dobra a cada tentativa: BaseDelay, 2*BaseDelay, 4*BaseDelay...
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.BaseDelay * time.Duration(1<<(attempt-1))
+}
+
+func RetryQueueName(queue string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, attempt)
+}
+
+func DeadLetterQueueName(queue string) string {
+	return queue + ".dlq"
+}
+
+// publishConfirmTimeout limita a espera pela confirmação do broker ao reencaminhar uma mensagem.
+const publishConfirmTimeout = 10 * time.Second
+
+// publisherChannel publica uma mensagem e só retorna nil depois de o broker a confirmar.
+type publisherChannel interface {
+	PublishConfirmed(ctx context.Context, queue string, msg amqp091.Publishing) error
+}
+
+// confirmChannel adapta um *amqp091.Channel em modo confirm a publisherChannel.
+type confirmChannel struct {
+	ch *amqp091.Channel
+}
+
+func (c confirmChannel) PublishConfirmed(ctx context.Context, queue string, msg amqp091.Publishing) error {
+	return publishConfirmed(ctx, c.ch, queue, msg)
+}
+
+// publishConfirmed publica msg na fila e espera pela confirmação; o canal tem de estar em modo confirm.
+func publishConfirmed(ctx context.Context, ch *amqp091.Channel, queue string, msg amqp091.Publishing) error {
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish a message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to confirm message: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("message was not acknowledged by the broker")
+	}
+	return nil
+}
+
+// RabbitMQConsumer consome uma fila com acknowledgement manual. Mensagens que falham são
+// reencaminhadas para filas de retry com TTL, que as devolvem à fila principal quando o
+// atraso expira; depois de esgotadas as tentativas vão para a dead-letter queue.
+type RabbitMQConsumer struct {
+	ch      publisherChannel
+	queue   string
+	policy  RetryPolicy
+	handler Handler
+
+	// ctx é passado aos handlers e só é cancelado quando o prazo do Shutdown expira.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu     sync.Mutex
+	status ConsumerStatus
+}
+
+func NewRabbitMQConsumer(ch *amqp091.Channel, queue string, policy RetryPolicy, handler Handler) (*RabbitMQConsumer, error) {
+	if err := declareTopology(ch, queue, policy); err != nil {
+		return nil, err
+	}
+	// Sem confirmações, uma mensagem reencaminhada para retry ou DLQ podia perder-se
+	// depois de a original já ter sido confirmada.
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return newConsumer(confirmChannel{ch: ch}, queue, policy, handler), nil
+}
+
+func newConsumer(ch publisherChannel, queue string, policy RetryPolicy, handler Handler) *RabbitMQConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RabbitMQConsumer{
+		ch:      ch,
+		queue:   queue,
+		policy:  policy,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		status:  ConsumerStatus{Queue: queue, State: ConsumerIdle},
+	}
+}
+
+func declareTopology(ch *amqp091.Channel, queue string, policy RetryPolicy) error {
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", queue, err)
+	}
+
+	for attempt := 1; attempt <= policy.MaxRetries; attempt++ {
+		args := amqp091.Table{
+			"x-message-ttl":             policy.delay(attempt).Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		}
+		name := RetryQueueName(queue, attempt)
+		if _, err := ch.QueueDeclare(name, true, false, false, false, args); err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", name, err)
+		}
+	}
+
+	dlq := DeadLetterQueueName(queue)
+	if _, err := ch.QueueDeclare(dlq, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue %s: %w", dlq, err)
+	}
+	return nil
+}
+
+// Consume processa as entregas até o canal de entregas ser fechado ou Shutdown ser chamado.
+func (c *RabbitMQConsumer) Consume(deliveries <-chan amqp091.Delivery) {
+	c.setState(ConsumerConsuming)
+	defer close(c.done)
+	defer c.setState(ConsumerStopped)
+	for {
+		select {
+		case <-c.stop:
+			c.requeue(deliveries)
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			// O select escolhe ao acaso quando há entregas e pedido de paragem ao mesmo tempo.
+			select {
+			case <-c.stop:
+				c.nack(d)
+				c.requeue(deliveries)
+				return
+			default:
+			}
+			c.handle(d)
+		}
+	}
+}
+
+// Shutdown pára o consumo: a mensagem em curso é processada até ao fim e as entregas já
+// recebidas (prefetch) mas ainda não processadas voltam para a fila. Se ctx expirar antes de
+// a mensagem em curso terminar, o contexto do handler é cancelado e o erro de ctx é devolvido.
+// Deve ser chamado antes de fechar o canal, depois de cancelar o consumidor no broker.
+func (c *RabbitMQConsumer) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		c.setState(ConsumerDraining)
+		close(c.stop)
+	})
+	select {
+	case <-c.done:
+		c.cancel()
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		return ctx.Err()
+	}
+}
+
+// requeue devolve à fila as entregas que já estão no buffer do canal.
+func (c *RabbitMQConsumer) requeue(deliveries <-chan amqp091.Delivery) {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			c.nack(d)
+		default:
+			return
+		}
+	}
+}
+
+func (c *RabbitMQConsumer) nack(d amqp091.Delivery) {
+	if err := d.Nack(false, true); err != nil {
+		log.Printf("Error requeueing message from %s: %s", c.queue, err)
+	}
+}
+
+func (c *RabbitMQConsumer) handle(d amqp091.Delivery) {
+	ctx := c.ctx
+	c.messageStarted()
+	defer func() { c.messageFinished(time.Now().UTC()) }()
+	messagesConsumed.WithLabelValues(c.queue).Inc()
+
+	err := c.handler(ctx, d.Body)
+	if err == nil {
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Printf("Error acknowledging message from %s: %s", c.queue, ackErr)
+		}
+		return
+	}
+	// Interrompida pelo prazo do shutdown: a falha não é da mensagem, não conta como tentativa.
+	if c.ctx.Err() != nil {
+		log.Printf("Message from %s interrupted by shutdown, requeueing: %s", c.queue, err)
+		messagesFailed.WithLabelValues(c.queue, "requeue").Inc()
+		c.nack(d)
+		return
+	}
+
+	retries := retryCount(d.Headers)
+	var permanent *permanentError
+	if errors.As(err, &permanent) || retries >= c.policy.MaxRetries {
+		log.Printf("Message from %s failed permanently after %d retries: %s", c.queue, retries, err)
+		messagesFailed.WithLabelValues(c.queue, "dead_letter").Inc()
+		c.forward(ctx, d, DeadLetterQueueName(c.queue), amqp091.Table{
+			headerRetryCount:    int32(retries),
+			headerFailureReason: err.Error(),
+			headerOriginalQueue: c.queue,
+			headerFailedAt:      time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	attempt := retries + 1
+	messagesFailed.WithLabelValues(c.queue, "retry").Inc()
+	log.Printf("Message from %s failed, scheduling retry %d/%d in %s: %s", c.queue, attempt, c.policy.MaxRetries, c.policy.delay(attempt), err)
+	c.forward(ctx, d, RetryQueueName(c.queue, attempt), amqp091.Table{
+		headerRetryCount: int32(attempt),
+		headerLastError:  err.Error(),
+	})
+}
+
+// forward republica a mensagem na fila indicada e só confirma a original depois de o broker
+// confirmar a republicação. Se esta falhar ou a confirmação não chegar, a original volta à fila.
+func (c *RabbitMQConsumer) forward(ctx context.Context, d amqp091.Delivery, queue string, headers amqp091.Table) {
+	merged := amqp091.Table{}
+	for k, v := range d.Headers {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishConfirmTimeout)
+	defer cancel()
+	err := c.ch.PublishConfirmed(ctx, queue, amqp091.Publishing{
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp091.Persistent,
+		Headers:      merged,
+		Body:         d.Body,
+	})
+	if err != nil {
+		log.Printf("Error forwarding message to %s: %s", queue, err)
+		c.nack(d)
+		return
+	}
+
+	if ackErr := d.Ack(false); ackErr != nil {
+		log.Printf("Error acknowledging message from %s: %s", c.queue, ackErr)
+	}
+}
+
+func retryCount(headers amqp091.Table) int {
+	switch v := headers[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
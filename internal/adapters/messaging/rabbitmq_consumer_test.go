@@ -0,0 +1,248 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAcknowledger regista acks e nacks por delivery tag.
+type fakeAcknowledger struct {
+	mu       sync.Mutex
+	acked    []uint64
+	requeued []uint64
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if requeue {
+		a.requeued = append(a.requeued, tag)
+	}
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) counts() (acked, requeued int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.acked), len(a.requeued)
+}
+
+// fakePublisherChannel regista as filas para onde as mensagens foram confirmadas; err simula
+// uma publicação que o broker não confirmou.
+type fakePublisherChannel struct {
+	mu        sync.Mutex
+	published []string
+	err       error
+}
+
+func (p *fakePublisherChannel) PublishConfirmed(ctx context.Context, queue string, msg amqp091.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, queue)
+	return nil
+}
+
+func delivery(ack amqp091.Acknowledger, tag uint64) amqp091.Delivery {
+	return amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte(`{}`)}
+}
+
+func TestConsumerShutdownDrainsInFlightDelivery(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handled := 0
+	c := newConsumer(&fakePublisherChannel{}, "jobs", RetryPolicy{MaxRetries: 1, BaseDelay: time.Second}, func(ctx context.Context, body []byte) error {
+		handled++
+		close(started)
+		<-release
+		return ctx.Err()
+	})
+
+	deliveries := make(chan amqp091.Delivery, 3)
+	deliveries <- delivery(ack, 1)
+	go c.Consume(deliveries)
+	<-started
+	if st := c.Status(); st.State != ConsumerConsuming || !st.InFlight {
+		t.Errorf("expected a consuming status with a message in flight, got %+v", st)
+	}
+
+	// Entregas que chegam (prefetch) enquanto a primeira está em curso.
+	deliveries <- delivery(ack, 2)
+	deliveries <- delivery(ack, 3)
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- c.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned before the in-flight delivery finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("expected only the in-flight delivery to be handled, got %d", handled)
+	}
+	if acked, requeued := ack.counts(); acked != 1 || requeued != 2 {
+		t.Errorf("expected 1 ack and 2 requeues, got %d acks and %d requeues", acked, requeued)
+	}
+	if st := c.Status(); st.State != ConsumerStopped || st.InFlight || st.Processed != 1 || st.LastMessageAt == nil {
+		t.Errorf("unexpected status after shutdown: %+v", st)
+	}
+}
+
+func TestConsumerShutdownDeadlineCancelsHandler(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	pub := &fakePublisherChannel{}
+	started := make(chan struct{})
+	c := newConsumer(pub, "jobs", RetryPolicy{MaxRetries: 1, BaseDelay: time.Second}, func(ctx context.Context, body []byte) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	deliveries := make(chan amqp091.Delivery, 1)
+	deliveries <- delivery(ack, 1)
+	consumed := make(chan struct{})
+	go func() {
+		c.Consume(deliveries)
+		close(consumed)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop after the handler context was cancelled")
+	}
+	// Interrompida pelo shutdown: volta à fila sem consumir uma tentativa.
+	if acked, requeued := ack.counts(); acked != 0 || requeued != 1 {
+		t.Errorf("expected the message to be requeued, got %d acks and %d requeues", acked, requeued)
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected no retry to be scheduled, got %v", pub.published)
+	}
+}
+
+func TestConsumerShutdownDeadlineAcksCommittedWork(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	pub := &fakePublisherChannel{}
+	started := make(chan struct{})
+	// Como o handler de assinaturas criadas: o trabalho já foi gravado quando o prazo expira
+	// a meio do e-mail, e uma nova entrega não o repetiria.
+	c := newConsumer(pub, "jobs", RetryPolicy{MaxRetries: 1, BaseDelay: time.Second}, func(ctx context.Context, body []byte) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+
+	deliveries := make(chan amqp091.Delivery, 1)
+	deliveries <- delivery(ack, 1)
+	consumed := make(chan struct{})
+	go func() {
+		c.Consume(deliveries)
+		close(consumed)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop after the handler context was cancelled")
+	}
+	if acked, requeued := ack.counts(); acked != 1 || requeued != 0 {
+		t.Errorf("expected the message to be acked, got %d acks and %d requeues", acked, requeued)
+	}
+	if len(pub.published) != 0 {
+		t.Errorf("expected no retry to be scheduled, got %v", pub.published)
+	}
+}
+
+func TestConsumerStopsWhenDeliveriesClose(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	c := newConsumer(&fakePublisherChannel{}, "closing", RetryPolicy{}, func(ctx context.Context, body []byte) error {
+		return nil
+	})
+
+	// As métricas são globais ao processo: compara-se com o valor antes de consumir.
+	before := testutil.ToFloat64(messagesConsumed.WithLabelValues("closing"))
+	deliveries := make(chan amqp091.Delivery, 2)
+	deliveries <- delivery(ack, 1)
+	deliveries <- delivery(ack, 2)
+	close(deliveries)
+	c.Consume(deliveries)
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if acked, _ := ack.counts(); acked != 2 {
+		t.Errorf("expected 2 acks, got %d", acked)
+	}
+	if got := testutil.ToFloat64(messagesConsumed.WithLabelValues("closing")) - before; got != 2 {
+		t.Errorf("expected 2 consumed messages, got %v", got)
+	}
+}
+
+func TestConsumerForwardsOnlyAfterConfirm(t *testing.T) {
+	failing := func(ctx context.Context, body []byte) error { return errors.New("boom") }
+
+	tests := []struct {
+		name         string
+		publishErr   error
+		wantAcked    int
+		wantRequeued int
+	}{
+		{name: "confirmed", wantAcked: 1},
+		{name: "not confirmed", publishErr: errors.New("message was not acknowledged by the broker"), wantRequeued: 1},
+	}
+
+	for _, tt := range tests {
+		ack := &fakeAcknowledger{}
+		pub := &fakePublisherChannel{err: tt.publishErr}
+		c := newConsumer(pub, "jobs", RetryPolicy{MaxRetries: 1, BaseDelay: time.Second}, failing)
+
+		deliveries := make(chan amqp091.Delivery, 1)
+		deliveries <- delivery(ack, 1)
+		close(deliveries)
+		c.Consume(deliveries)
+
+		if acked, requeued := ack.counts(); acked != tt.wantAcked || requeued != tt.wantRequeued {
+			t.Errorf("%s: got %d acks and %d requeues, want %d and %d", tt.name, acked, requeued, tt.wantAcked, tt.wantRequeued)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -23,10 +24,23 @@ func NewRabbitMQPublisher(amqpURL string) (*RabbitMQPublisher, error) {
 		return nil, fmt.Errorf("failed to open a channel: %w", err)
 	}
 
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	return &RabbitMQPublisher{conn: conn, ch: ch}, nil
 }
 
 func (p *RabbitMQPublisher) Publish(ctx context.Context, queueName string, body []byte) error {
+	if err := p.publish(ctx, queueName, body); err != nil {
+		messagesPublishFailed.WithLabelValues(queueName).Inc()
+		return err
+	}
+	messagesPublished.WithLabelValues(queueName).Inc()
+	return nil
+}
+
+func (p *RabbitMQPublisher) publish(ctx context.Context, queueName string, body []byte) error {
 	_, err := p.ch.QueueDeclare(
 		queueName,
 		true,
@@ -39,19 +53,21 @@ func (p *RabbitMQPublisher) Publish(ctx context.Context, queueName string, body
 		return fmt.Errorf("failed to declare a queue: %w", err)
 	}
 
-	err = p.ch.PublishWithContext(ctx,
-		"",
-		queueName,
-		false,
-		false,
-		amqp091.Publishing{
-			ContentType: "text/plain",
-			Body:        body,
-		})
-	if err != nil {
-		return fmt.Errorf("failed to publish a message: %w", err)
-	}
+	return publishConfirmed(ctx, p.ch, queueName, amqp091.Publishing{
+		ContentType:  "text/plain",
+		DeliveryMode: amqp091.Persistent,
+		Body:         body,
+	})
+}
 
+// Ping falha quando a conexão ou o canal com o RabbitMQ foram fechados (ex.: queda do broker).
+func (p *RabbitMQPublisher) Ping(ctx context.Context) error {
+	if p.conn.IsClosed() {
+		return errors.New("rabbitmq connection is closed")
+	}
+	if p.ch.IsClosed() {
+		return errors.New("rabbitmq channel is closed")
+	}
 	return nil
 }
 
@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// Company são os dados do emissor impressos no cabeçalho das faturas.
+type Company struct {
+	Name    string
+	Address string
+	TaxID   string
+	Email   string
+}
+
+// InvoiceRenderer gera as faturas em PDF com as fontes padrão do formato, sem dependências
+// fora do Go.
+type InvoiceRenderer struct {
+	company Company
+}
+
+func NewInvoiceRenderer(company Company) *InvoiceRenderer {
+	return &InvoiceRenderer{company: company}
+}
+
+const (
+	pageMargin   = 15.0
+	contentWidth = 210 - 2*pageMargin
+	amountWidth  = 40.0
+	lineHeight   = 6.0
+)
+
+func (r *InvoiceRenderer) Render(inv *domain.Invoice, customer *domain.User) ([]byte, error) {
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.SetMargins(pageMargin, pageMargin, pageMargin)
+	doc.SetAutoPageBreak(true, pageMargin)
+	doc.SetCreationDate(inv.CreatedAt)
+	doc.SetTitle("Invoice "+inv.Number, true)
+	doc.SetAuthor(r.company.Name, true)
+	doc.AddPage()
+
+	// As fontes padrão usam cp1252; o tradutor converte acentos e símbolos como € e £.
+	tr := doc.UnicodeTranslatorFromDescriptor("")
+
+	r.writeHeader(doc, tr, inv)
+	writeCustomer(doc, tr, inv, customer)
+	writeLines(doc, tr, inv)
+	writeTotals(doc, tr, inv)
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		return nil, fmt.Errorf("rendering invoice %s: %w", inv.Number, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *InvoiceRenderer) writeHeader(doc *fpdf.Fpdf, tr func(string) string, inv *domain.Invoice) {
+	top := doc.GetY()
+
+	doc.SetFont("Helvetica", "B", 16)
+	doc.CellFormat(contentWidth/2, 8, tr(r.company.Name), "", 2, "L", false, 0, "")
+	doc.SetFont("Helvetica", "", 9)
+	for _, line := range []string{r.company.Address, r.company.TaxID, r.company.Email} {
+		if line != "" {
+			doc.CellFormat(contentWidth/2, 5, tr(line), "", 2, "L", false, 0, "")
+		}
+	}
+	bottom := doc.GetY()
+
+	doc.SetXY(pageMargin+contentWidth/2, top)
+	doc.SetFont("Helvetica", "B", 16)
+	doc.CellFormat(contentWidth/2, 8, "INVOICE", "", 2, "R", false, 0, "")
+	doc.SetFont("Helvetica", "", 9)
+	doc.CellFormat(contentWidth/2, 5, inv.Number, "", 2, "R", false, 0, "")
+	doc.CellFormat(contentWidth/2, 5, "Issued "+inv.CreatedAt.Format(time.DateOnly), "", 2, "R", false, 0, "")
+	status := "Amount due"
+	if inv.PaidAt != nil {
+		status = "Paid " + inv.PaidAt.Format(time.DateOnly)
+	}
+	doc.CellFormat(contentWidth/2, 5, status, "", 2, "R", false, 0, "")
+
+	doc.SetXY(pageMargin, max(bottom, doc.GetY())+4)
+	doc.Line(pageMargin, doc.GetY(), pageMargin+contentWidth, doc.GetY())
+	doc.Ln(6)
+}
+
+func writeCustomer(doc *fpdf.Fpdf, tr func(string) string, inv *domain.Invoice, customer *domain.User) {
+	doc.SetFont("Helvetica", "B", 10)
+	doc.CellFormat(contentWidth/2, lineHeight, "Bill to", "", 0, "L", false, 0, "")
+	doc.CellFormat(contentWidth/2, lineHeight, "Billing period", "", 1, "R", false, 0, "")
+
+	doc.SetFont("Helvetica", "", 10)
+	doc.CellFormat(contentWidth/2, lineHeight, tr(customer.Name), "", 0, "L", false, 0, "")
+	period := inv.PeriodStart.Format(time.DateOnly) + " - " + inv.PeriodEnd.Format(time.DateOnly)
+	doc.CellFormat(contentWidth/2, lineHeight, period, "", 1, "R", false, 0, "")
+	doc.CellFormat(contentWidth/2, lineHeight, tr(customer.Email), "", 0, "L", false, 0, "")
+	doc.CellFormat(contentWidth/2, lineHeight, "Subscription "+inv.SubscriptionID, "", 1, "R", false, 0, "")
+	doc.Ln(8)
+}
+
+func writeLines(doc *fpdf.Fpdf, tr func(string) string, inv *domain.Invoice) {
+	doc.SetFont("Helvetica", "B", 10)
+	doc.SetFillColor(235, 235, 235)
+	doc.CellFormat(contentWidth-amountWidth, 8, "Description", "B", 0, "L", true, 0, "")
+	doc.CellFormat(amountWidth, 8, "Amount", "B", 1, "R", true, 0, "")
+
+	// O imposto aparece nos totais, não como item.
+	doc.SetFont("Helvetica", "", 10)
+	for _, line := range inv.Lines {
+		if line.Type == domain.InvoiceLineTax {
+			continue
+		}
+		doc.CellFormat(contentWidth-amountWidth, 7, tr(line.Description), "B", 0, "L", false, 0, "")
+		doc.CellFormat(amountWidth, 7, tr(FormatMoney(line.Amount, inv.Currency)), "B", 1, "R", false, 0, "")
+	}
+	doc.Ln(4)
+}
+
+func writeTotals(doc *fpdf.Fpdf, tr func(string) string, inv *domain.Invoice) {
+	labelWidth := contentWidth - amountWidth
+	total := func(label string, amount int64) {
+		doc.CellFormat(labelWidth, lineHeight, tr(label), "", 0, "R", false, 0, "")
+		doc.CellFormat(amountWidth, lineHeight, tr(FormatMoney(amount, inv.Currency)), "", 1, "R", false, 0, "")
+	}
+
+	doc.SetFont("Helvetica", "", 10)
+	total("Subtotal", inv.Subtotal)
+	for _, line := range inv.Lines {
+		if line.Type == domain.InvoiceLineTax {
+			total(line.Description, line.Amount)
+		}
+	}
+	doc.SetFont("Helvetica", "B", 11)
+	total("Total", inv.Total)
+	if inv.PaidAt != nil {
+		total("Amount paid", inv.AmountDue())
+	} else {
+		total("Amount due", inv.AmountDue())
+	}
+}
+
+type currencyFormat struct {
+	symbol    string
+	decimal   string
+	thousands string
+}
+
+// Moedas fora da tabela usam o código ISO como prefixo.
+var currencyFormats = map[string]currencyFormat{
+	"BRL": {symbol: "R$ ", decimal: ",", thousands: "."},
+	"EUR": {symbol: "€ ", decimal: ",", thousands: "."},
+	"USD": {symbol: "$", decimal: ".", thousands: ","},
+	"GBP": {symbol: "£", decimal: ".", thousands: ","},
+}
+
+// minorUnits é o número de casas decimais das moedas que não usam duas (ISO 4217). Para estas
+// moedas o valor guardado já está na menor unidade da moeda (ex: ienes, não centésimos de iene).
+var minorUnits = map[string]int{
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// FormatMoney formata um valor na menor unidade da moeda segundo a sua convenção,
+// ex: 123456 BRL → "R$ 1.234,56" e 1234 JPY → "JPY 1,234".
+func FormatMoney(amount int64, currency string) string {
+	format, ok := currencyFormats[currency]
+	if !ok {
+		format = currencyFormat{symbol: currency + " ", decimal: ".", thousands: ","}
+	}
+	exponent, ok := minorUnits[currency]
+	if !ok {
+		exponent = 2
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	scale := int64(1)
+	for range exponent {
+		scale *= 10
+	}
+	units := strconv.FormatInt(amount/scale, 10)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteString(format.thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+	if exponent == 0 {
+		return sign + format.symbol + grouped.String()
+	}
+	return fmt.Sprintf("%s%s%s%s%0*d", sign, format.symbol, grouped.String(), format.decimal, exponent, amount%scale)
+}
@@ -0,0 +1,51 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{123456, "BRL", "R$ 1.234,56"},
+		{5, "BRL", "R$ 0,05"},
+		{-2990, "BRL", "-R$ 29,90"},
+		{100000000, "USD", "$1,000,000.00"},
+		{999, "EUR", "€ 9,99"},
+		{12345, "CHF", "CHF 123.45"},
+		{1234567, "JPY", "JPY 1,234,567"},
+		{-4990, "CLP", "-CLP 4,990"},
+		{12345, "KWD", "KWD 12.345"},
+	}
+	for _, tt := range tests {
+		if got := FormatMoney(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatMoney(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestRenderInvoice(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	sub := &domain.Subscription{ID: "sub-1", UserID: "user-1"}
+	inv := domain.NewInvoice(sub, domain.InvoiceReasonRenewal, "EUR", now, now.AddDate(0, 1, 0), now)
+	inv.Number = domain.FormatInvoiceNumber(42)
+	inv.AddLine(domain.InvoiceLinePlan, "Pro (2025-07-01 - 2025-08-01)", 2990)
+	inv.ApplyTax(2300)
+	inv.MarkPaid("ch_1", now)
+
+	renderer := NewInvoiceRenderer(Company{Name: "Assinaturas Lda.", Address: "Rua Augusta 1, Lisboa"})
+	doc, err := renderer.Render(inv, &domain.User{Name: "João Conceição", Email: "joao@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(doc, []byte("%PDF-")) {
+		t.Errorf("output is not a PDF: %q", doc[:min(len(doc), 16)])
+	}
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
+)
+
+type AdminHandler struct {
+	subService  *subscription.Service
+	authService *auth.AuthService
+}
+
+func NewAdminHandler(subService *subscription.Service, authService *auth.AuthService) *AdminHandler {
+	return &AdminHandler{
+		subService:  subService,
+		authService: authService,
+	}
+}
+
+func (h *AdminHandler) GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	user, err := h.authService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AdminHandler) ListSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	input, err := parseListInput(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.subService.AdminListSubscriptions(r.Context(), r.URL.Query().Get("userId"), input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *AdminHandler) CancelSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.subService.ForceCancelSubscription(r.Context(), subscriptionID, expectedVersion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *AdminHandler) ReactivateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.subService.ForceReactivateSubscription(r.Context(), subscriptionID, expectedVersion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
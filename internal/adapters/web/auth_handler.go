@@ -8,27 +8,25 @@ import (
 )
 
 type AuthHandler struct {
-	service   *auth.AuthService
-	jwtSecret string
+	service *auth.AuthService
 }
 
-func NewAuthHandler(s *auth.AuthService, jwtSecret string) *AuthHandler {
+func NewAuthHandler(s *auth.AuthService) *AuthHandler {
 	return &AuthHandler{
-		service:   s,
-		jwtSecret: jwtSecret,
+		service: s,
 	}
 }
 
 func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	var input auth.RegisterInput
 	if err := decodeAndValidate(r, &input); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	user, err := h.service.Register(r.Context(), input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+		writeError(w, r, err)
 		return
 	}
 
@@ -40,23 +38,56 @@ func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var input auth.LoginInput
 	if err := decodeAndValidate(r, &input); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	user, err := h.service.Login(r.Context(), input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	token, err := auth.GenerateJWT(user.ID, h.jwtSecret)
+	tokens, err := h.service.StartSession(r.Context(), user)
 	if err != nil {
-		http.Error(w, "could not generate token", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var input auth.RefreshInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.service.Refresh(r.Context(), input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var input auth.RefreshInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuzokas/subscription-api/internal/core/coupon"
+)
+
+type CouponHandler struct {
+	service *coupon.Service
+}
+
+func NewCouponHandler(s *coupon.Service) *CouponHandler {
+	return &CouponHandler{
+		service: s,
+	}
+}
+
+func (h *CouponHandler) CreateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	var input coupon.CouponInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	c, err := h.service.CreateCoupon(r.Context(), input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+func (h *CouponHandler) ListCouponsHandler(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("includeInactive") == "true"
+
+	coupons, err := h.service.ListCoupons(r.Context(), includeInactive)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupons)
+}
+
+func (h *CouponHandler) DeactivateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	couponID := chi.URLParam(r, "id")
+
+	c, err := h.service.DeactivateCoupon(r.Context(), couponID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
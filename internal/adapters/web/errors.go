@@ -0,0 +1,197 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+const problemContentType = "application/problem+json"
+
+var errInvalidQuery = errors.New("invalid query parameter")
+
+// Problem é o corpo de erro no formato RFC 7807. Code é um identificador estável que
+// os clientes podem usar em vez de interpretar a mensagem.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorMapping struct {
+	target error
+	status int
+	code   string
+}
+
+// errorMappings traduz os erros de domínio e de autenticação para respostas HTTP.
+// A ordem importa: a primeira correspondência (via errors.Is) é usada.
+var errorMappings = []errorMapping{
+	{domain.ErrSubscriptionNotFound, http.StatusNotFound, "subscription_not_found"},
+	{domain.ErrPlanNotFound, http.StatusNotFound, "plan_not_found"},
+	{domain.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{domain.ErrInvoiceNotFound, http.StatusNotFound, "invoice_not_found"},
+	{domain.ErrCouponNotFound, http.StatusNotFound, "coupon_not_found"},
+	{domain.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{domain.ErrPlanInactive, http.StatusUnprocessableEntity, "plan_inactive"},
+	{domain.ErrCouponCodeTaken, http.StatusConflict, "coupon_code_taken"},
+	{domain.ErrCouponInactive, http.StatusUnprocessableEntity, "coupon_inactive"},
+	{domain.ErrCouponExpired, http.StatusUnprocessableEntity, "coupon_expired"},
+	{domain.ErrCouponExhausted, http.StatusUnprocessableEntity, "coupon_exhausted"},
+	{domain.ErrCouponNotApplicable, http.StatusUnprocessableEntity, "coupon_not_applicable"},
+	{domain.ErrCouponAlreadyRedeemed, http.StatusConflict, "coupon_already_redeemed"},
+	{domain.ErrSubscriptionCannotBeCancelled, http.StatusConflict, "subscription_cannot_be_cancelled"},
+	{domain.ErrSubscriptionNotRenewable, http.StatusConflict, "subscription_not_renewable"},
+	{domain.ErrInvalidTransition, http.StatusConflict, "invalid_status_transition"},
+	{domain.ErrPlanUnchanged, http.StatusConflict, "plan_unchanged"},
+	{domain.ErrPlanChangeNotAllowed, http.StatusConflict, "plan_change_not_allowed"},
+	{domain.ErrPlanCurrencyMismatch, http.StatusUnprocessableEntity, "plan_currency_mismatch"},
+	{domain.ErrTrialPlanFamilyChange, http.StatusUnprocessableEntity, "trial_plan_family_mismatch"},
+	{domain.ErrNoScheduledCancellation, http.StatusConflict, "no_scheduled_cancellation"},
+	{domain.ErrInvalidResumeDate, http.StatusUnprocessableEntity, "invalid_resume_date"},
+	{domain.ErrPaymentFailed, http.StatusPaymentRequired, "payment_failed"},
+	{payment.ErrGatewayUnavailable, http.StatusServiceUnavailable, "payment_gateway_unavailable"},
+	{domain.ErrVersionConflict, http.StatusConflict, "version_conflict"},
+	{subscription.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{domain.ErrRefreshTokenReused, http.StatusUnauthorized, "refresh_token_reused"},
+	{auth.ErrUserAlreadyExists, http.StatusConflict, "user_already_exists"},
+	{auth.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{auth.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token"},
+	{subscription.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{errInvalidQuery, http.StatusBadRequest, "invalid_query"},
+	{errInvalidIfMatch, http.StatusBadRequest, "invalid_if_match"},
+	{errMissingIfMatch, http.StatusPreconditionRequired, "precondition_required"},
+	{idempotency.ErrKeyReused, http.StatusConflict, "idempotency_key_reused"},
+	{idempotency.ErrRequestInProgress, http.StatusConflict, "idempotency_request_in_progress"},
+}
+
+// writeError escreve a resposta problem+json correspondente a err. Erros desconhecidos
+// são registados no log e devolvidos como 500 sem expor detalhes internos.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		writeValidationProblem(w, r, validationErrs)
+		return
+	}
+
+	if errors.Is(err, errMalformedBody) {
+		writeProblem(w, r, http.StatusBadRequest, "malformed_body", errMalformedBody.Error())
+		return
+	}
+
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			writeProblem(w, r, m.status, m.code, err.Error())
+			return
+		}
+	}
+
+	log.Printf("Unhandled error on %s %s: %s", r.Method, r.URL.Path, err)
+	writeProblem(w, r, http.StatusInternalServerError, "internal_error", "an unexpected error occurred")
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblemBody(w, Problem{
+		Type:     "/problems/" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	})
+}
+
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, errs validator.ValidationErrors) {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Code:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+
+	writeProblemBody(w, Problem{
+		Type:     "/problems/validation_failed",
+		Title:    http.StatusText(http.StatusBadRequest),
+		Status:   http.StatusBadRequest,
+		Detail:   "one or more fields are invalid",
+		Instance: r.URL.Path,
+		Code:     "validation_failed",
+		Errors:   fields,
+	})
+}
+
+func writeProblemBody(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// jsonFieldName converte o nome Go de um campo referido numa tag (ex: "AmountOff") para o
+// nome usado no JSON ("amountOff").
+func jsonFieldName(field string) string {
+	if field == "" {
+		return field
+	}
+	return strings.ToLower(field[:1]) + field[1:]
+}
+
+// sizeMessage descreve os limites de min/max/len: nos textos e listas o limite é um tamanho,
+// nos números é o próprio valor.
+func sizeMessage(fe validator.FieldError, bound string) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must contain %s %s items", bound, fe.Param())
+	default:
+		return fmt.Sprintf("must be %s %s", bound, fe.Param())
+	}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "is required"
+	case "excluded_with":
+		return "cannot be combined with " + jsonFieldName(fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return sizeMessage(fe, "at least")
+	case "max":
+		return sizeMessage(fe, "at most")
+	case "len":
+		return sizeMessage(fe, "exactly")
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	default:
+		return "is invalid"
+	}
+}
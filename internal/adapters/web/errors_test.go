@@ -0,0 +1,135 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, problemContentType)
+	}
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("decoding problem: %v", err)
+	}
+	return p
+}
+
+func TestWriteErrorMapsKnownErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{domain.ErrSubscriptionNotFound, http.StatusNotFound, "subscription_not_found"},
+		{fmt.Errorf("loading: %w", domain.ErrForbidden), http.StatusForbidden, "forbidden"},
+		{&domain.InvalidTransitionError{From: domain.StatusCancelled, To: domain.StatusPastDue}, http.StatusConflict, "invalid_status_transition"},
+		{auth.ErrUserAlreadyExists, http.StatusConflict, "user_already_exists"},
+		{domain.ErrCouponExpired, http.StatusUnprocessableEntity, "coupon_expired"},
+		{fmt.Errorf("loading plan: %w", domain.ErrPlanNotFound), http.StatusNotFound, "plan_not_found"},
+		{domain.ErrPlanInactive, http.StatusUnprocessableEntity, "plan_inactive"},
+		{domain.ErrTrialPlanFamilyChange, http.StatusUnprocessableEntity, "trial_plan_family_mismatch"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/1", nil), tt.err)
+
+			p := decodeProblem(t, rec)
+			if rec.Code != tt.status || p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("got status %d/%d code %q, want %d %q", rec.Code, p.Status, p.Code, tt.status, tt.code)
+			}
+			if p.Instance != "/subscriptions/1" {
+				t.Errorf("instance = %q", p.Instance)
+			}
+		})
+	}
+}
+
+func TestWriteErrorDoesNotLeakInternalErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("pq: password authentication failed"))
+
+	if p := decodeProblem(t, rec); strings.Contains(p.Detail, "password") {
+		t.Errorf("internal error leaked in detail: %q", p.Detail)
+	}
+}
+
+func TestDecodeAndValidateReportsFieldErrors(t *testing.T) {
+	body := strings.NewReader(`{"name": "A", "email": "not-an-email"}`)
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", body)
+
+	var input auth.RegisterInput
+	err := decodeAndValidate(req, &input)
+
+	rec := httptest.NewRecorder()
+	writeError(rec, req, err)
+
+	p := decodeProblem(t, rec)
+	if rec.Code != http.StatusBadRequest || p.Code != "validation_failed" {
+		t.Fatalf("got %d %q, want 400 validation_failed", rec.Code, p.Code)
+	}
+
+	got := map[string]string{}
+	for _, fe := range p.Errors {
+		got[fe.Field] = fe.Code
+	}
+	want := map[string]string{"name": "min", "email": "email", "password": "required"}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("field %q code = %q, want %q (all: %v)", field, got[field], code, got)
+		}
+	}
+}
+
+func TestValidationMessagesDependOnTheFieldKind(t *testing.T) {
+	body := strings.NewReader(`{"name": "A", "price": 0, "planIds": []}`)
+	req := httptest.NewRequest(http.MethodPost, "/plans", body)
+
+	var input struct {
+		Name    string   `json:"name" validate:"min=2"`
+		Price   int64    `json:"price" validate:"min=1"`
+		PlanIDs []string `json:"planIds" validate:"min=1"`
+	}
+	rec := httptest.NewRecorder()
+	writeError(rec, req, decodeAndValidate(req, &input))
+
+	got := map[string]string{}
+	for _, fe := range decodeProblem(t, rec).Errors {
+		got[fe.Field] = fe.Message
+	}
+	want := map[string]string{
+		"name":    "must be at least 2 characters long",
+		"price":   "must be at least 1",
+		"planIds": "must contain at least 1 items",
+	}
+	for field, message := range want {
+		if got[field] != message {
+			t.Errorf("field %q message = %q, want %q", field, got[field], message)
+		}
+	}
+}
+
+func TestDecodeAndValidateRejectsMalformedJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":`))
+
+	var input auth.LoginInput
+	rec := httptest.NewRecorder()
+	writeError(rec, req, decodeAndValidate(req, &input))
+
+	if p := decodeProblem(t, rec); rec.Code != http.StatusBadRequest || p.Code != "malformed_body" {
+		t.Errorf("got %d %q, want 400 malformed_body", rec.Code, p.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be a single entity tag previously returned in ETag, or *")
+var errMissingIfMatch = errors.New("this request requires an If-Match header with the subscription ETag, or * to skip the check")
+
+// subscriptionETag deriva a ETag da versão da assinatura; qualquer gravação gera uma nova.
+func subscriptionETag(sub *domain.Subscription) string {
+	return `"` + strconv.Itoa(sub.Version) + `"`
+}
+
+func setSubscriptionETag(w http.ResponseWriter, sub *domain.Subscription) {
+	w.Header().Set("ETag", subscriptionETag(sub))
+}
+
+// parseIfMatch devolve a versão exigida pelo cabeçalho If-Match. O cabeçalho é obrigatório
+// para que nenhuma alteração sobrescreva outra às cegas; "*" dispensa a verificação de forma
+// explícita (subscription.AnyVersion). ETags fracas (W/) são aceites pois a versão identifica
+// o recurso de forma exata.
+func parseIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, errMissingIfMatch
+	}
+	if value == "*" {
+		return subscription.AnyVersion, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
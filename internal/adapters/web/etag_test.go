@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/manuzokas/subscription-api/internal/core/subscription"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{header: "*", want: subscription.AnyVersion},
+		{header: `"3"`, want: 3},
+		{header: `W/"7"`, want: 7},
+		{header: `3`, wantErr: true},
+		{header: `"abc"`, wantErr: true},
+		{header: `"0"`, wantErr: true},
+		{header: `"1", "2"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("DELETE", "/subscriptions/sub-1", nil)
+		if tt.header != "" {
+			req.Header.Set("If-Match", tt.header)
+		}
+
+		got, err := parseIfMatch(req)
+		if tt.wantErr {
+			if !errors.Is(err, errInvalidIfMatch) {
+				t.Errorf("parseIfMatch(%q) error = %v, want errInvalidIfMatch", tt.header, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseIfMatch(%q) = %d, %v; want %d", tt.header, got, err, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionETagRoundTrip(t *testing.T) {
+	sub := &domain.Subscription{ID: "sub-1", Version: 4}
+
+	req := httptest.NewRequest("DELETE", "/subscriptions/sub-1", nil)
+	req.Header.Set("If-Match", subscriptionETag(sub))
+
+	got, err := parseIfMatch(req)
+	if err != nil || got != sub.Version {
+		t.Fatalf("parseIfMatch(ETag) = %d, %v; want %d", got, err, sub.Version)
+	}
+}
+
+func TestMutatingSubscriptionEndpointsRequireIfMatch(t *testing.T) {
+	h := NewSubscriptionHandler(nil)
+	handlers := map[string]http.HandlerFunc{
+		"cancel":      h.CancelSubscriptionHandler,
+		"pause":       h.PauseSubscriptionHandler,
+		"resume":      h.ResumeSubscriptionHandler,
+		"reactivate":  h.ReactivateSubscriptionHandler,
+		"change-plan": h.ChangePlanHandler,
+	}
+
+	for name, handler := range handlers {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions/sub-1/"+name, strings.NewReader(`{}`))
+		req = req.WithContext(context.WithValue(req.Context(), UserIDContextKey, "user-1"))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if p := decodeProblem(t, rec); rec.Code != http.StatusPreconditionRequired || p.Code != "precondition_required" {
+			t.Errorf("%s without If-Match: got %d %q, want 428 precondition_required", name, rec.Code, p.Code)
+		}
+	}
+}
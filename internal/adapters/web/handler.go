@@ -2,12 +2,12 @@ package web
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/manuzokas/subscription-api/internal/core/subscription"
-	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
 type SubscriptionHandler struct {
@@ -23,49 +23,85 @@ func NewSubscriptionHandler(s *subscription.Service) *SubscriptionHandler {
 func (h *SubscriptionHandler) CreateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(UserIDContextKey).(string)
 	if !ok {
-		http.Error(w, "invalid user ID in context", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
 		return
 	}
 
 	var input subscription.CreateSubscriptionInput
 	if err := decodeAndValidate(r, &input); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	sub, err := h.service.CreateSubscription(r.Context(), userID, input)
 	if err != nil {
-		http.Error(w, "could not create subscription", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	setSubscriptionETag(w, sub)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(sub)
 }
 
+func (h *SubscriptionHandler) ListSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+
+	input, err := parseListInput(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.ListSubscriptions(r.Context(), userID, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func parseListInput(r *http.Request) (subscription.ListInput, error) {
+	q := r.URL.Query()
+	input := subscription.ListInput{
+		Status: q.Get("status"),
+		PlanID: q.Get("planId"),
+		Sort:   q.Get("sort"),
+		Cursor: q.Get("cursor"),
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return input, fmt.Errorf("%w: limit must be an integer", errInvalidQuery)
+		}
+		input.Limit = n
+	}
+	return input, validate.Struct(input)
+}
+
 func (h *SubscriptionHandler) GetSubscriptionByIDHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(UserIDContextKey).(string)
 	if !ok {
-		http.Error(w, "invalid user ID in context", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
 		return
 	}
 	subscriptionID := chi.URLParam(r, "id")
 
 	sub, err := h.service.GetSubscriptionByID(r.Context(), userID, subscriptionID)
 	if err != nil {
-		if errors.Is(err, domain.ErrSubscriptionNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		if errors.Is(err, domain.ErrForbidden) {
-			http.Error(w, err.Error(), http.StatusForbidden)
-			return
-		}
-		http.Error(w, "could not retrieve subscription", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	setSubscriptionETag(w, sub)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(sub)
@@ -74,28 +110,193 @@ func (h *SubscriptionHandler) GetSubscriptionByIDHandler(w http.ResponseWriter,
 func (h *SubscriptionHandler) CancelSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(UserIDContextKey).(string)
 	if !ok {
-		http.Error(w, "invalid user ID in context", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
 		return
 	}
 	subscriptionID := chi.URLParam(r, "id")
 
-	err := h.service.CancelSubscription(r.Context(), userID, subscriptionID)
+	expectedVersion, err := parseIfMatch(r)
 	if err != nil {
-		if errors.Is(err, domain.ErrSubscriptionNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		if errors.Is(err, domain.ErrForbidden) {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, r, err)
+		return
+	}
+
+	atPeriodEnd := false
+	if raw := r.URL.Query().Get("atPeriodEnd"); raw != "" {
+		if atPeriodEnd, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, r, fmt.Errorf("%w: atPeriodEnd must be a boolean", errInvalidQuery))
 			return
 		}
-		if errors.Is(err, domain.ErrSubscriptionCannotBeCancelled) {
-			http.Error(w, err.Error(), http.StatusConflict)
+	}
+
+	// O cancelamento agendado mantém a assinatura ativa, por isso devolvemos o estado atualizado.
+	if atPeriodEnd {
+		sub, err := h.service.ScheduleCancellation(r.Context(), userID, subscriptionID, expectedVersion)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
-		http.Error(w, "could not cancel subscription", http.StatusInternalServerError)
+
+		setSubscriptionETag(w, sub)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sub)
+		return
+	}
+
+	err = h.service.CancelSubscription(r.Context(), userID, subscriptionID, expectedVersion)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *SubscriptionHandler) ReactivateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.service.ReactivateSubscription(r.Context(), userID, subscriptionID, expectedVersion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *SubscriptionHandler) AttachPaymentMethodHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var input subscription.AttachPaymentMethodInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.service.AttachPaymentMethod(r.Context(), userID, subscriptionID, expectedVersion, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *SubscriptionHandler) ChangePlanHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var input subscription.ChangePlanInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.ChangePlan(r.Context(), userID, subscriptionID, expectedVersion, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, result.Subscription)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *SubscriptionHandler) PauseSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var input subscription.PauseInput
+	if err := decodeOptionalAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.service.PauseSubscription(r.Context(), userID, subscriptionID, expectedVersion, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *SubscriptionHandler) ResumeSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	subscriptionID := chi.URLParam(r, "id")
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sub, err := h.service.ResumeSubscription(r.Context(), userID, subscriptionID, expectedVersion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSubscriptionETag(w, sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sub)
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout limita cada verificação de prontidão para que uma dependência presa
+// não bloqueie a sonda.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck verifica uma dependência externa; nil significa que está disponível.
+type HealthCheck func(ctx context.Context) error
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	checks map[string]HealthCheck
+}
+
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks: checks,
+	}
+}
+
+// LivenessHandler responde sempre 200 enquanto o processo estiver a servir pedidos.
+func (h *HealthHandler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// ReadinessHandler executa todas as verificações e responde 503 se alguma falhar.
+func (h *HealthHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{Status: "ready", Checks: make(map[string]string, len(h.checks))}
+	status := http.StatusOK
+	for name, check := range h.checks {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		err := check(ctx)
+		cancel()
+		if err != nil {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	writeHealth(w, status, resp)
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("rabbitmq connection is closed") }
+
+	tests := []struct {
+		name       string
+		checks     map[string]HealthCheck
+		wantStatus int
+		wantBody   HealthResponse
+	}{
+		{
+			name:       "all dependencies up",
+			checks:     map[string]HealthCheck{"postgres": ok, "rabbitmq": ok},
+			wantStatus: http.StatusOK,
+			wantBody:   HealthResponse{Status: "ready", Checks: map[string]string{"postgres": "ok", "rabbitmq": "ok"}},
+		},
+		{
+			name:       "one dependency down",
+			checks:     map[string]HealthCheck{"postgres": ok, "rabbitmq": down},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   HealthResponse{Status: "unavailable", Checks: map[string]string{"postgres": "ok", "rabbitmq": "rabbitmq connection is closed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		NewHealthHandler(tt.checks).ReadinessHandler(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+		var got HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: decoding body: %v", tt.name, err)
+		}
+		if got.Status != tt.wantBody.Status || len(got.Checks) != len(tt.wantBody.Checks) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.wantBody)
+			continue
+		}
+		for name, want := range tt.wantBody.Checks {
+			if got.Checks[name] != want {
+				t.Errorf("%s: check %s = %q, want %q", tt.name, name, got.Checks[name], want)
+			}
+		}
+	}
+}
+
+func TestLivenessHandlerIgnoresDependencies(t *testing.T) {
+	h := NewHealthHandler(map[string]HealthCheck{
+		"postgres": func(ctx context.Context) error { return errors.New("down") },
+	})
+	rec := httptest.NewRecorder()
+	h.LivenessHandler(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
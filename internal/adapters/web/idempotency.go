@@ -0,0 +1,107 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware repete a resposta gravada quando um cliente reenvia um pedido com
+// a mesma Idempotency-Key. Pedidos sem o cabeçalho seguem o fluxo normal.
+// Deve ser usado depois de AuthMiddleware, pois as chaves são isoladas por utilizador.
+func IdempotencyMiddleware(service *idempotency.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				writeProblem(w, r, http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			userID, ok := r.Context().Value(UserIDContextKey).(string)
+			if !ok {
+				writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, r, errMalformedBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			requestHash := idempotency.HashRequest(r.Method, r.URL.Path, body)
+			stored, err := service.Begin(r.Context(), userID, key, requestHash)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if stored != nil {
+				for name, values := range stored.Headers {
+					w.Header()[name] = values
+				}
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			// Corre também se o cliente desistiu (contexto cancelado) ou se o handler entrou em
+			// pânico, para que a chave nunca fique "em curso" até expirar.
+			defer func() {
+				ctx := context.WithoutCancel(r.Context())
+				if completed {
+					err := service.Finish(ctx, userID, key, idempotency.StoredResponse{
+						StatusCode:  rec.status,
+						ContentType: rec.Header().Get("Content-Type"),
+						Headers:     rec.Header().Clone(),
+						Body:        rec.body.Bytes(),
+					})
+					if err == nil {
+						return
+					}
+					log.Printf("Error storing idempotent response for key %s: %s", key, err)
+				}
+				if err := service.Release(ctx, userID, key); err != nil {
+					log.Printf("Error releasing idempotency key %s: %s", key, err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+			completed = true
+		})
+	}
+}
+
+// responseRecorder escreve a resposta para o cliente e guarda uma cópia para ser gravada.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
@@ -0,0 +1,153 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+)
+
+type memoryIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{records: map[string]*idempotency.Record{}}
+}
+
+func (m *memoryIdempotencyRepo) Reserve(_ context.Context, rec *idempotency.Record) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := rec.UserID + "/" + rec.Key
+	if existing, ok := m.records[id]; ok && existing.ExpiresAt.After(rec.CreatedAt) {
+		return existing, nil
+	}
+	m.records[id] = rec
+	return nil, nil
+}
+
+// Complete e Release falham com um contexto cancelado, tal como as queries do pgx.
+func (m *memoryIdempotencyRepo) Complete(ctx context.Context, userID, key string, resp idempotency.StoredResponse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[userID+"/"+key].Response = &resp
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) Release(ctx context.Context, userID, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, userID+"/"+key)
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) DeleteExpired(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"sub-1"}`))
+	})
+	mw := IdempotencyMiddleware(idempotency.NewService(newMemoryIdempotencyRepo(), time.Hour))(handler)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(body))
+		req.Header.Set(idempotencyKeyHeader, key)
+		req = req.WithContext(context.WithValue(req.Context(), UserIDContextKey, "user-1"))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("key-1", `{"planId":"p1"}`)
+	if first.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: status %d, calls %d", first.Code, calls)
+	}
+
+	replay := send("key-1", `{"planId":"p1"}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != `{"id":"sub-1"}` || calls != 1 {
+		t.Fatalf("replay: status %d, body %q, calls %d", replay.Code, replay.Body.String(), calls)
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected Idempotent-Replayed header on replay")
+	}
+	if replay.Header().Get("ETag") != `"1"` || replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers differ from the original: %v", replay.Header())
+	}
+
+	conflict := send("key-1", `{"planId":"p2"}`)
+	if conflict.Code != http.StatusConflict || calls != 1 {
+		t.Fatalf("reused key with different body: status %d, calls %d", conflict.Code, calls)
+	}
+
+	other := send("key-2", `{"planId":"p1"}`)
+	if other.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("new key: status %d, calls %d", other.Code, calls)
+	}
+}
+
+func sendIdempotent(ctx context.Context, mw http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(`{"planId":"p1"}`))
+	req.Header.Set(idempotencyKeyHeader, key)
+	req = req.WithContext(context.WithValue(ctx, UserIDContextKey, "user-1"))
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddlewareStoresResponseAfterClientDisconnect(t *testing.T) {
+	calls := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		cancel() // o cliente desiste enquanto o pedido é processado
+		w.WriteHeader(http.StatusCreated)
+	})
+	mw := IdempotencyMiddleware(idempotency.NewService(newMemoryIdempotencyRepo(), time.Hour))(handler)
+
+	sendIdempotent(ctx, mw, "key-1")
+	retry := sendIdempotent(context.Background(), mw, "key-1")
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Fatalf("retry after disconnect: status %d, replayed %q, calls %d", retry.Code, retry.Header().Get("Idempotent-Replayed"), calls)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mw := IdempotencyMiddleware(idempotency.NewService(newMemoryIdempotencyRepo(), time.Hour))(handler)
+
+	func() {
+		defer func() { recover() }()
+		sendIdempotent(context.Background(), mw, "key-1")
+	}()
+
+	retry := sendIdempotent(context.Background(), mw, "key-1")
+	if retry.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("retry after panic: status %d, calls %d", retry.Code, calls)
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuzokas/subscription-api/internal/core/invoice"
+)
+
+type InvoiceHandler struct {
+	service *invoice.Service
+}
+
+func NewInvoiceHandler(s *invoice.Service) *InvoiceHandler {
+	return &InvoiceHandler{
+		service: s,
+	}
+}
+
+func (h *InvoiceHandler) ListInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+
+	input := invoice.ListInput{SubscriptionID: r.URL.Query().Get("subscriptionId")}
+
+	result, err := h.service.ListInvoices(r.Context(), userID, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *InvoiceHandler) GetInvoiceByIDHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	invoiceID := chi.URLParam(r, "id")
+
+	inv, err := h.service.GetInvoice(r.Context(), userID, invoiceID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(inv)
+}
+
+func (h *InvoiceHandler) GetInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid user ID in context")
+		return
+	}
+	invoiceID := chi.URLParam(r, "id")
+
+	inv, doc, err := h.service.RenderInvoice(r.Context(), userID, invoiceID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", inv.Number+".pdf"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc)
+}
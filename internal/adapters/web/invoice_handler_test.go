@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manuzokas/subscription-api/internal/core/invoice"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+const testJWTSecret = "test-secret"
+
+type memoryInvoices map[string]*domain.Invoice
+
+func (m memoryInvoices) FindByID(ctx context.Context, id string) (*domain.Invoice, error) {
+	inv, ok := m[id]
+	if !ok {
+		return nil, domain.ErrInvoiceNotFound
+	}
+	return inv, nil
+}
+
+func (m memoryInvoices) ListByUser(ctx context.Context, userID string, filter invoice.ListFilter) ([]*domain.Invoice, error) {
+	return nil, nil
+}
+
+type invoiceUsers struct{}
+
+func (invoiceUsers) FindUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return &domain.User{ID: id, Name: "Ana", Email: "ana@example.com"}, nil
+}
+
+// stubRenderer devolve um documento fixo e regista quantas faturas renderizou.
+type stubRenderer struct {
+	rendered int
+}
+
+func (r *stubRenderer) Render(inv *domain.Invoice, customer *domain.User) ([]byte, error) {
+	r.rendered++
+	return []byte("%PDF-1.3 " + inv.ID), nil
+}
+
+type activeSessions struct{}
+
+func (activeSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return true, nil
+}
+
+func bearerToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID, "jti": "session-" + userID}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func TestInvoicePDFRoute(t *testing.T) {
+	inv := &domain.Invoice{ID: "inv-1", UserID: "user-1", Number: domain.FormatInvoiceNumber(7), Currency: "BRL"}
+	renderer := &stubRenderer{}
+	service := invoice.NewService(memoryInvoices{inv.ID: inv}, invoiceUsers{}, renderer)
+	router := SetupRouter(nil, nil, nil, nil, NewInvoiceHandler(service), nil, nil, nil, testJWTSecret, activeSessions{})
+
+	get := func(path, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", bearerToken(t, userID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/invoices/inv-1.pdf", "user-1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("owner download: got %d %q, want 200 application/pdf", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `attachment; filename="` + inv.Number + `.pdf"`; rec.Header().Get("Content-Disposition") != want {
+		t.Errorf("Content-Disposition = %q, want %q", rec.Header().Get("Content-Disposition"), want)
+	}
+	if rec.Body.String() != "%PDF-1.3 inv-1" {
+		t.Errorf("body = %q, want the rendered document", rec.Body.String())
+	}
+
+	// Sem o sufixo .pdf a mesma fatura é devolvida em JSON.
+	rec = get("/invoices/inv-1", "user-1")
+	var got domain.Invoice
+	if err := json.NewDecoder(rec.Body).Decode(&got); rec.Code != http.StatusOK || err != nil || got.ID != inv.ID {
+		t.Errorf("GET /invoices/inv-1: got %d %+v (%v), want the invoice as JSON", rec.Code, got, err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		userID     string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "another user's invoice", path: "/invoices/inv-1.pdf", userID: "user-2", wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "unknown invoice", path: "/invoices/inv-2.pdf", userID: "user-1", wantStatus: http.StatusNotFound, wantCode: "invoice_not_found"},
+	}
+	for _, tt := range tests {
+		rec := get(tt.path, tt.userID)
+		if p := decodeProblem(t, rec); rec.Code != tt.wantStatus || p.Code != tt.wantCode {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, rec.Code, p.Code, tt.wantStatus, tt.wantCode)
+		}
+	}
+	if renderer.rendered != 1 {
+		t.Errorf("expected only the owner's download to be rendered, got %d renders", renderer.rendered)
+	}
+}
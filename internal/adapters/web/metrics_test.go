@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddlewareLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.Route("/things", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+	})
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/things/{id}", "418"))
+	unmatchedBefore := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404"))
+	for _, path := range []string{"/things/1", "/things/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/things/{id}", "418")) - before; got != 2 {
+		t.Errorf("expected 2 requests for /things/{id}, got %v", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")) - unmatchedBefore; got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+}
+
+func TestPublicRouterDoesNotServeMetrics(t *testing.T) {
+	router := SetupRouter(nil, nil, nil, nil, nil, nil, nil, nil, testJWTSecret, activeSessions{})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if p := decodeProblem(t, rec); rec.Code != http.StatusNotFound || p.Code != "route_not_found" {
+		t.Errorf("GET /metrics on the public router: got %d %q, want 404 route_not_found", rec.Code, p.Code)
+	}
+}
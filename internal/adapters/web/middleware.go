@@ -6,24 +6,32 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
 type contextKey string
 
 const UserIDContextKey = contextKey("userID")
+const SessionIDContextKey = contextKey("sessionID")
+const RoleContextKey = contextKey("role")
 
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// SessionValidator permite rejeitar access tokens cuja sessão (jti) foi revogada antes de expirarem.
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+func AuthMiddleware(jwtSecret string, sessions SessionValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				writeProblem(w, r, http.StatusUnauthorized, "missing_authorization", "Authorization header required")
 				return
 			}
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString == authHeader {
-				http.Error(w, "Invalid token format", http.StatusUnauthorized)
+				writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "Invalid token format")
 				return
 			}
 
@@ -32,24 +40,73 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 			})
 
 			if err != nil || !token.Valid {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "Invalid token")
 				return
 			}
 
 			claims, ok := token.Claims.(jwt.MapClaims)
 			if !ok {
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+				writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "Invalid token claims")
 				return
 			}
 
 			userID, ok := claims["sub"].(string)
 			if !ok {
-				http.Error(w, "Invalid user ID in token", http.StatusUnauthorized)
+				writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "Invalid user ID in token")
+				return
+			}
+
+			sessionID, ok := claims["jti"].(string)
+			if !ok {
+				writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "Invalid session ID in token")
+				return
+			}
+
+			active, err := sessions.IsSessionActive(r.Context(), sessionID)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if !active {
+				writeProblem(w, r, http.StatusUnauthorized, "session_revoked", "Session has been revoked")
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
+			ctx = context.WithValue(ctx, SessionIDContextKey, sessionID)
+			ctx = context.WithValue(ctx, RoleContextKey, roleFromClaims(claims))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// RequireRole só deixa passar pedidos cujo token tenha uma das roles indicadas.
+// Deve ser usado depois de AuthMiddleware.
+func RequireRole(roles ...domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(RoleContextKey).(domain.Role)
+			if !ok {
+				writeProblem(w, r, http.StatusInternalServerError, "internal_error", "invalid role in context")
+				return
+			}
+
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeError(w, r, domain.ErrForbidden)
+		})
+	}
+}
+
+// roleFromClaims trata tokens sem a claim role como pertencentes a um cliente.
+func roleFromClaims(claims jwt.MapClaims) domain.Role {
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		return domain.RoleCustomer
+	}
+	return domain.Role(role)
+}
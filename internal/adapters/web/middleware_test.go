@@ -0,0 +1,121 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// revokedSessions considera ativas todas as sessões exceto as indicadas.
+type revokedSessions map[string]bool
+
+func (r revokedSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return !r[sessionID], nil
+}
+
+func TestAuthMiddlewareRejectsRevokedSessions(t *testing.T) {
+	handler := AuthMiddleware(testJWTSecret, revokedSessions{"session-user-2": true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		userID     string
+		wantStatus int
+	}{
+		{userID: "user-1", wantStatus: http.StatusNoContent},
+		{userID: "user-2", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions", nil)
+		req.Header.Set("Authorization", bearerToken(t, tt.userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d", tt.userID, rec.Code, tt.wantStatus)
+		}
+		if tt.wantStatus == http.StatusUnauthorized {
+			if p := decodeProblem(t, rec); p.Code != "session_revoked" {
+				t.Errorf("%s: code = %q, want session_revoked", tt.userID, p.Code)
+			}
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole(domain.RoleAdmin, domain.RoleSupport)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		role       domain.Role
+		wantStatus int
+	}{
+		{domain.RoleAdmin, http.StatusNoContent},
+		{domain.RoleSupport, http.StatusNoContent},
+		{domain.RoleCustomer, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/subscriptions", nil)
+		req = req.WithContext(context.WithValue(req.Context(), RoleContextKey, tt.role))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.role, rec.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestRoleClaimRoundTripsThroughTheToken(t *testing.T) {
+	var got domain.Role
+	handler := AuthMiddleware(testJWTSecret, activeSessions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(RoleContextKey).(domain.Role)
+	}))
+
+	for _, role := range []domain.Role{domain.RoleCustomer, domain.RoleSupport, domain.RoleAdmin} {
+		token, err := auth.GenerateJWT("user-1", role, "session-1", testJWTSecret, time.Minute)
+		if err != nil {
+			t.Fatalf("GenerateJWT() error = %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != role {
+			t.Errorf("role in context = %q, want %q", got, role)
+		}
+	}
+
+	// Tokens emitidos antes da claim role pertencem a clientes.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", bearerToken(t, "user-1"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != domain.RoleCustomer {
+		t.Errorf("token without role claim: role = %q, want %q", got, domain.RoleCustomer)
+	}
+}
+
+func TestSupportCannotUseAdminMutations(t *testing.T) {
+	router := SetupRouter(nil, nil, nil, nil, nil, nil, nil, nil, testJWTSecret, activeSessions{})
+	token, err := auth.GenerateJWT("user-1", domain.RoleSupport, "session-1", testJWTSecret, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateJWT() error = %v", err)
+	}
+
+	for _, path := range []string{"/admin/subscriptions/sub-1/cancel", "/admin/subscriptions/sub-1/reactivate", "/admin/coupons"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if p := decodeProblem(t, rec); rec.Code != http.StatusForbidden || p.Code != "forbidden" {
+			t.Errorf("POST %s as support: got %d %q, want 403 forbidden", path, rec.Code, p.Code)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuzokas/subscription-api/internal/core/plan"
+)
+
+type PlanHandler struct {
+	service *plan.Service
+}
+
+func NewPlanHandler(s *plan.Service) *PlanHandler {
+	return &PlanHandler{
+		service: s,
+	}
+}
+
+func (h *PlanHandler) CreatePlanHandler(w http.ResponseWriter, r *http.Request) {
+	var input plan.PlanInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, err := h.service.CreatePlan(r.Context(), input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PlanHandler) ListPlansHandler(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("includeInactive") == "true"
+
+	plans, err := h.service.ListPlans(r.Context(), includeInactive)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(plans)
+}
+
+func (h *PlanHandler) GetPlanByIDHandler(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+
+	p, err := h.service.GetPlan(r.Context(), planID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PlanHandler) UpdatePlanHandler(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+
+	var input plan.PlanInput
+	if err := decodeAndValidate(r, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, err := h.service.UpdatePlan(r.Context(), planID, input)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PlanHandler) ArchivePlanHandler(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+
+	if err := h.service.ArchivePlan(r.Context(), planID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,25 +5,92 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/manuzokas/subscription-api/internal/core/idempotency"
+	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
-func SetupRouter(subHandler *SubscriptionHandler, authHandler *AuthHandler, jwtSecret string) http.Handler {
+func SetupRouter(subHandler *SubscriptionHandler, authHandler *AuthHandler, planHandler *PlanHandler, adminHandler *AdminHandler, invoiceHandler *InvoiceHandler, couponHandler *CouponHandler, healthHandler *HealthHandler, idempotencyService *idempotency.Service, jwtSecret string, sessions SessionValidator) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(MetricsMiddleware)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, "route_not_found", "the requested route does not exist")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed for this route")
+	})
+
+	r.Get("/healthz", healthHandler.LivenessHandler)
+	r.Get("/readyz", healthHandler.ReadinessHandler)
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.RegisterHandler)
 		r.Post("/login", authHandler.LoginHandler)
+		r.Post("/refresh", authHandler.RefreshHandler)
+		r.Post("/logout", authHandler.LogoutHandler)
 	})
 
 	r.Route("/subscriptions", func(r chi.Router) {
-		r.Use(AuthMiddleware(jwtSecret))
+		r.Use(AuthMiddleware(jwtSecret, sessions))
 
-		r.Post("/", subHandler.CreateSubscriptionHandler)
+		r.Get("/", subHandler.ListSubscriptionsHandler)
+		r.With(IdempotencyMiddleware(idempotencyService)).Post("/", subHandler.CreateSubscriptionHandler)
 		r.Get("/{id}", subHandler.GetSubscriptionByIDHandler)
 		r.Delete("/{id}", subHandler.CancelSubscriptionHandler)
+		r.Put("/{id}/payment-method", subHandler.AttachPaymentMethodHandler)
+		r.Post("/{id}/change-plan", subHandler.ChangePlanHandler)
+		r.Post("/{id}/pause", subHandler.PauseSubscriptionHandler)
+		r.Post("/{id}/resume", subHandler.ResumeSubscriptionHandler)
+		r.Post("/{id}/reactivate", subHandler.ReactivateSubscriptionHandler)
+	})
+
+	r.Route("/invoices", func(r chi.Router) {
+		r.Use(AuthMiddleware(jwtSecret, sessions))
+
+		r.Get("/", invoiceHandler.ListInvoicesHandler)
+		r.Get("/{id}.pdf", invoiceHandler.GetInvoicePDFHandler)
+		r.Get("/{id}", invoiceHandler.GetInvoiceByIDHandler)
+	})
+
+	r.Route("/plans", func(r chi.Router) {
+		r.Use(AuthMiddleware(jwtSecret, sessions))
+
+		r.Get("/", planHandler.ListPlansHandler)
+		r.Get("/{id}", planHandler.GetPlanByIDHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(RequireRole(domain.RoleAdmin))
+
+			r.Post("/", planHandler.CreatePlanHandler)
+			r.Put("/{id}", planHandler.UpdatePlanHandler)
+			r.Delete("/{id}", planHandler.ArchivePlanHandler)
+		})
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(AuthMiddleware(jwtSecret, sessions))
+
+		// O suporte pode consultar utilizadores e assinaturas, mas não alterá-los.
+		r.Group(func(r chi.Router) {
+			r.Use(RequireRole(domain.RoleAdmin, domain.RoleSupport))
+
+			r.Get("/users/{id}", adminHandler.GetUserByIDHandler)
+			r.Get("/subscriptions", adminHandler.ListSubscriptionsHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(RequireRole(domain.RoleAdmin))
+
+			r.Post("/subscriptions/{id}/cancel", adminHandler.CancelSubscriptionHandler)
+			r.Post("/subscriptions/{id}/reactivate", adminHandler.ReactivateSubscriptionHandler)
+
+			r.Get("/coupons", couponHandler.ListCouponsHandler)
+			r.Post("/coupons", couponHandler.CreateCouponHandler)
+			r.Post("/coupons/{id}/deactivate", couponHandler.DeactivateCouponHandler)
+		})
 	})
 
 	return r
@@ -2,16 +2,44 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+var errMalformedBody = errors.New("request body must be valid JSON")
+
+// newValidator reporta os campos pelo nome usado no JSON (ex: "planId" em vez de "PlanID").
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
 
 func decodeAndValidate(r *http.Request, v interface{}) error {
 	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
-		return err
+		return fmt.Errorf("%w: %s", errMalformedBody, err)
+	}
+	return validate.Struct(v)
+}
+
+// decodeOptionalAndValidate aceita um corpo vazio, para endpoints cujos campos são todos opcionais.
+func decodeOptionalAndValidate(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s", errMalformedBody, err)
 	}
 	return validate.Struct(v)
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFromEnv lê uma duração (ex: "30s", "5m") da variável de ambiente key,
+// devolvendo fallback quando a variável está vazia ou é inválida.
+func DurationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// IntFromEnv lê um inteiro da variável de ambiente key, devolvendo fallback quando a
+// variável está vazia ou é inválida.
+func IntFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// IntListFromEnv lê uma lista de inteiros separados por vírgula (ex: "1,3,7") da variável de
+// ambiente key, devolvendo fallback quando a variável está vazia ou algum valor é inválido.
+func IntListFromEnv(key string, fallback []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var list []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			log.Printf("Warning: invalid %s %q, using %v", key, value, fallback)
+			return fallback
+		}
+		list = append(list, n)
+	}
+	return list
+}
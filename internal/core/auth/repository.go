@@ -11,3 +11,14 @@ type UserRepository interface {
 	FindUserByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindUserByID(ctx context.Context, id string) (*domain.User, error)
 }
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	// FindRefreshTokenByHash devolve domain.ErrRefreshTokenNotFound se o hash não existir.
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	// RotateRefreshToken revoga oldID, marcando-o como substituído por next, e grava next
+	// na mesma transação. Devolve domain.ErrRefreshTokenReused se oldID já tiver sido revogado.
+	RotateRefreshToken(ctx context.Context, oldID string, next *domain.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	IsFamilyActive(ctx context.Context, familyID string) (bool, error)
+}
@@ -11,13 +11,36 @@ import (
 
 var ErrUserAlreadyExists = errors.New("user with this email already exists")
 var ErrInvalidCredentials = errors.New("invalid email or password")
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+type TokenConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
 
 type AuthService struct {
-	repo UserRepository
+	repo      UserRepository
+	tokenRepo TokenRepository
+	tokens    TokenConfig
+}
+
+func NewAuthService(repo UserRepository, tokenRepo TokenRepository, tokens TokenConfig) *AuthService {
+	return &AuthService{
+		repo:      repo,
+		tokenRepo: tokenRepo,
+		tokens:    tokens,
+	}
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
 }
 
-func NewAuthService(repo UserRepository) *AuthService {
-	return &AuthService{repo: repo}
+type RefreshInput struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
 }
 
 type RegisterInput struct {
@@ -43,6 +66,7 @@ func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*domai
 		Name:         input.Name,
 		Email:        input.Email,
 		PasswordHash: hashedPassword,
+		Role:         domain.RoleCustomer,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -71,3 +95,107 @@ func (s *AuthService) Login(ctx context.Context, input LoginInput) (*domain.User
 
 	return user, nil
 }
+
+// StartSession inicia uma nova família de refresh tokens para o utilizador autenticado.
+func (s *AuthService) StartSession(ctx context.Context, user *domain.User) (*TokenPair, error) {
+	return s.issueTokens(ctx, user, uuid.NewString(), nil)
+}
+
+// Refresh troca um refresh token válido por um novo par de tokens. O token apresentado é
+// revogado; se um token já revogado for reapresentado, toda a família é revogada, já que
+// isso indica que o token foi roubado.
+func (s *AuthService) Refresh(ctx context.Context, input RefreshInput) (*TokenPair, error) {
+	current, err := s.findRefreshToken(ctx, input.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.IsRevoked() {
+		if err := s.tokenRepo.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrRefreshTokenReused
+	}
+	if current.IsExpired(time.Now().UTC()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// O utilizador é relido para que mudanças de role passem a valer na próxima renovação.
+	user, err := s.repo.FindUserByID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := s.issueTokens(ctx, user, current.FamilyID, current)
+	if errors.Is(err, domain.ErrRefreshTokenReused) {
+		if revokeErr := s.tokenRepo.RevokeFamily(ctx, current.FamilyID); revokeErr != nil {
+			return nil, revokeErr
+		}
+	}
+	return pair, err
+}
+
+// Logout revoga a sessão (família) a que o refresh token pertence.
+func (s *AuthService) Logout(ctx context.Context, input RefreshInput) error {
+	current, err := s.findRefreshToken(ctx, input.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeFamily(ctx, current.FamilyID)
+}
+
+// findRefreshToken traduz um token desconhecido em ErrInvalidRefreshToken; outros erros (ex:
+// base de dados indisponível) são devolvidos tal como estão.
+func (s *AuthService) findRefreshToken(ctx context.Context, token string) (*domain.RefreshToken, error) {
+	current, err := s.tokenRepo.FindRefreshTokenByHash(ctx, HashRefreshToken(token))
+	if errors.Is(err, domain.ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	return current, err
+}
+
+func (s *AuthService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return s.repo.FindUserByID(ctx, id)
+}
+
+// IsSessionActive indica se a sessão identificada pelo jti de um access token ainda não foi revogada.
+func (s *AuthService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.tokenRepo.IsFamilyActive(ctx, sessionID)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User, familyID string, previous *domain.RefreshToken) (*TokenPair, error) {
+	accessToken, err := GenerateJWT(user.ID, user.Role, familyID, s.tokens.JWTSecret, s.tokens.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, err := GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	next := &domain.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(s.tokens.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+
+	if previous == nil {
+		err = s.tokenRepo.CreateRefreshToken(ctx, next)
+	} else {
+		err = s.tokenRepo.RotateRefreshToken(ctx, previous.ID, next)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.tokens.AccessTokenTTL.Seconds()),
+	}, nil
+}
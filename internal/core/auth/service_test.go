@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type memoryUsers struct {
+	users map[string]*domain.User
+}
+
+func (m *memoryUsers) CreateUser(_ context.Context, user *domain.User) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *memoryUsers) FindUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memoryUsers) FindUserByID(_ context.Context, id string) (*domain.User, error) {
+	if u, ok := m.users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+// memoryTokens reproduz as garantias do repositório Postgres, incluindo a rotação condicionada
+// a o token ainda não estar revogado. findErr simula uma falha da base de dados.
+type memoryTokens struct {
+	tokens  map[string]*domain.RefreshToken
+	findErr error
+}
+
+func newMemoryTokens() *memoryTokens {
+	return &memoryTokens{tokens: make(map[string]*domain.RefreshToken)}
+}
+
+func (m *memoryTokens) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *memoryTokens) FindRefreshTokenByHash(_ context.Context, hash string) (*domain.RefreshToken, error) {
+	if m.findErr != nil {
+		return nil, m.findErr
+	}
+	for _, t := range m.tokens {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrRefreshTokenNotFound
+}
+
+func (m *memoryTokens) RotateRefreshToken(_ context.Context, oldID string, next *domain.RefreshToken) error {
+	old := m.tokens[oldID]
+	if old.RevokedAt != nil {
+		return domain.ErrRefreshTokenReused
+	}
+	old.RevokedAt = &next.CreatedAt
+	old.ReplacedByID = &next.ID
+	m.tokens[next.ID] = next
+	return nil
+}
+
+func (m *memoryTokens) RevokeFamily(_ context.Context, familyID string) error {
+	now := time.Now().UTC()
+	for _, t := range m.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *memoryTokens) IsFamilyActive(_ context.Context, familyID string) (bool, error) {
+	now := time.Now().UTC()
+	for _, t := range m.tokens {
+		if t.FamilyID == familyID && !t.IsRevoked() && !t.IsExpired(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestAuthService() (*AuthService, *memoryTokens, *domain.User) {
+	user := &domain.User{ID: "user-1", Email: "ana@example.com", Role: domain.RoleCustomer}
+	tokens := newMemoryTokens()
+	users := &memoryUsers{users: map[string]*domain.User{user.ID: user}}
+	service := NewAuthService(users, tokens, TokenConfig{JWTSecret: "secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour})
+	return service, tokens, user
+}
+
+// sessionOf devolve a família (jti) do refresh token apresentado.
+func sessionOf(t *testing.T, tokens *memoryTokens, refreshToken string) string {
+	t.Helper()
+	current, err := tokens.FindRefreshTokenByHash(context.Background(), HashRefreshToken(refreshToken))
+	if err != nil {
+		t.Fatalf("refresh token not stored: %v", err)
+	}
+	return current.FamilyID
+}
+
+func TestRefreshRotatesTheRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	service, tokens, user := newTestAuthService()
+
+	first, err := service.StartSession(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := service.Refresh(ctx, RefreshInput{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+	if sessionOf(t, tokens, second.RefreshToken) != sessionOf(t, tokens, first.RefreshToken) {
+		t.Error("expected the rotated token to stay in the same family")
+	}
+	if old, _ := tokens.FindRefreshTokenByHash(ctx, HashRefreshToken(first.RefreshToken)); !old.IsRevoked() || old.ReplacedByID == nil {
+		t.Errorf("expected the presented token to be revoked and replaced, got %+v", old)
+	}
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: second.RefreshToken}); err != nil {
+		t.Errorf("refreshing with the rotated token: %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheWholeFamily(t *testing.T) {
+	ctx := context.Background()
+	service, tokens, user := newTestAuthService()
+
+	stolen, err := service.StartSession(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session := sessionOf(t, tokens, stolen.RefreshToken)
+	rotated, err := service.Refresh(ctx, RefreshInput{RefreshToken: stolen.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: stolen.RefreshToken}); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Fatalf("reusing a rotated token: got %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: rotated.RefreshToken}); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Errorf("the legitimate token must be revoked with its family, got %v", err)
+	}
+	if active, _ := service.IsSessionActive(ctx, session); active {
+		t.Error("expected the session to be revoked")
+	}
+}
+
+func TestLogoutRevokesTheSession(t *testing.T) {
+	ctx := context.Background()
+	service, tokens, user := newTestAuthService()
+
+	pair, err := service.StartSession(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session := sessionOf(t, tokens, pair.RefreshToken)
+	other, err := service.StartSession(ctx, user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.Logout(ctx, RefreshInput{RefreshToken: pair.RefreshToken}); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if active, _ := service.IsSessionActive(ctx, session); active {
+		t.Error("expected the session to be revoked after logout")
+	}
+	if active, _ := service.IsSessionActive(ctx, sessionOf(t, tokens, other.RefreshToken)); !active {
+		t.Error("logout must not revoke the user's other sessions")
+	}
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: pair.RefreshToken}); err == nil {
+		t.Error("expected the logged out refresh token to be rejected")
+	}
+}
+
+func TestRefreshLookupErrors(t *testing.T) {
+	ctx := context.Background()
+	service, tokens, _ := newTestAuthService()
+
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: "unknown"}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: got %v, want ErrInvalidRefreshToken", err)
+	}
+
+	outage := errors.New("connection refused")
+	tokens.findErr = outage
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: "unknown"}); !errors.Is(err, outage) {
+		t.Errorf("database outage: got %v, want the repository error", err)
+	}
+	if err := service.Logout(ctx, RefreshInput{RefreshToken: "unknown"}); !errors.Is(err, outage) {
+		t.Errorf("database outage on logout: got %v, want the repository error", err)
+	}
+}
@@ -1,17 +1,25 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
-func GenerateJWT(userID string, jwtSecret string) (string, error) {
+func GenerateJWT(userID string, role domain.Role, sessionID string, jwtSecret string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-		"iat": time.Now().Unix(),
+		"sub":  userID,
+		"role": string(role),
+		"jti":  sessionID,
+		"exp":  now.Add(ttl).Unix(),
+		"iat":  now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -23,3 +31,18 @@ func GenerateJWT(userID string, jwtSecret string) (string, error) {
 
 	return tokenString, nil
 }
+
+// GenerateRefreshToken devolve um token opaco aleatório e o hash que deve ser persistido.
+func GenerateRefreshToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("could not generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package coupon
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// Repository guarda o catálogo de cupons. Os resgates são gravados junto com a assinatura
+// que os usou (ver subscription.Repository.SaveWithRedemption).
+//
+// Save devolve domain.ErrCouponCodeTaken quando o código já pertence a outro cupom; a consulta
+// feita por CreateCoupon não basta para criações concorrentes com o mesmo código.
+type Repository interface {
+	Save(ctx context.Context, c *domain.Coupon) error
+	FindByID(ctx context.Context, id string) (*domain.Coupon, error)
+	FindByCode(ctx context.Context, code string) (*domain.Coupon, error)
+	List(ctx context.Context, includeInactive bool) ([]*domain.Coupon, error)
+	HasRedeemed(ctx context.Context, couponID, userID string) (bool, error)
+}
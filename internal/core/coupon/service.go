@@ -0,0 +1,101 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manuzokas/subscription-api/internal/core/plan"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type Service struct {
+	repo     Repository
+	planRepo plan.Repository
+}
+
+func NewService(repo Repository, planRepo plan.Repository) *Service {
+	return &Service{repo: repo, planRepo: planRepo}
+}
+
+// CouponInput descreve um novo cupom: o desconto é percentOff ou amountOff (com currency),
+// nunca os dois.
+type CouponInput struct {
+	Code              string                `json:"code" validate:"required,min=3,max=50"`
+	PercentOff        int                   `json:"percentOff" validate:"required_without=AmountOff,excluded_with=AmountOff,gte=0,lte=100"`
+	AmountOff         int64                 `json:"amountOff" validate:"required_without=PercentOff,gte=0"`
+	Currency          string                `json:"currency" validate:"required_with=AmountOff,omitempty,len=3"`
+	Duration          domain.CouponDuration `json:"duration" validate:"required,oneof=ONCE REPEATING FOREVER"`
+	DurationInPeriods int                   `json:"durationInPeriods" validate:"required_if=Duration REPEATING,gte=0"`
+	MaxRedemptions    int                   `json:"maxRedemptions" validate:"gte=0"`
+	ExpiresAt         *time.Time            `json:"expiresAt"`
+	PlanIDs           []string              `json:"planIds"`
+}
+
+// NormalizeCode torna os códigos insensíveis a maiúsculas e espaços à volta.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (s *Service) CreateCoupon(ctx context.Context, input CouponInput) (*domain.Coupon, error) {
+	code := NormalizeCode(input.Code)
+	if _, err := s.repo.FindByCode(ctx, code); err == nil {
+		return nil, domain.ErrCouponCodeTaken
+	} else if !errors.Is(err, domain.ErrCouponNotFound) {
+		return nil, err
+	}
+
+	for _, planID := range input.PlanIDs {
+		if _, err := s.planRepo.FindByID(ctx, planID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	c := &domain.Coupon{
+		ID:             uuid.NewString(),
+		Code:           code,
+		PercentOff:     input.PercentOff,
+		AmountOff:      input.AmountOff,
+		Duration:       input.Duration,
+		MaxRedemptions: input.MaxRedemptions,
+		ExpiresAt:      input.ExpiresAt,
+		PlanIDs:        input.PlanIDs,
+		Active:         true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if c.AmountOff > 0 {
+		c.Currency = strings.ToUpper(input.Currency)
+	}
+	if c.Duration == domain.CouponDurationRepeating {
+		c.DurationInPeriods = input.DurationInPeriods
+	}
+	if c.PlanIDs == nil {
+		c.PlanIDs = []string{}
+	}
+
+	if err := s.repo.Save(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) ListCoupons(ctx context.Context, includeInactive bool) ([]*domain.Coupon, error) {
+	return s.repo.List(ctx, includeInactive)
+}
+
+// DeactivateCoupon impede novos resgates sem retirar o desconto de quem já o usou.
+func (s *Service) DeactivateCoupon(ctx context.Context, id string) (*domain.Coupon, error) {
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Deactivate(time.Now().UTC())
+	if err := s.repo.Save(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
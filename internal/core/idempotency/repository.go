@@ -0,0 +1,15 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Reserve grava a chave como "em curso". Se já existir um registo não expirado para
+	// o mesmo utilizador e chave, nada é gravado e o registo existente é devolvido.
+	Reserve(ctx context.Context, rec *Record) (existing *Record, err error)
+	Complete(ctx context.Context, userID, key string, resp StoredResponse) error
+	Release(ctx context.Context, userID, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var ErrKeyReused = errors.New("idempotency key was already used with a different request")
+var ErrRequestInProgress = errors.New("a request with this idempotency key is still being processed")
+
+// Record guarda o resultado de um pedido identificado por uma Idempotency-Key.
+// Response fica vazio enquanto o pedido original ainda está a ser processado.
+type Record struct {
+	UserID      string
+	Key         string
+	RequestHash string
+	Response    *StoredResponse
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// StoredResponse é a resposta repetida ao cliente. Headers guarda os cabeçalhos definidos pelo
+// handler (ex.: ETag, Location); ContentType existe para registos gravados antes de Headers.
+type StoredResponse struct {
+	StatusCode  int
+	ContentType string
+	Headers     map[string][]string
+	Body        []byte
+}
+
+type Service struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+func NewService(repo Repository, ttl time.Duration) *Service {
+	return &Service{repo: repo, ttl: ttl}
+}
+
+// HashRequest identifica o conteúdo de um pedido para detetar a reutilização de uma chave
+// com um corpo diferente.
+func HashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserva a chave para um novo pedido. Quando a chave já foi usada com o mesmo pedido
+// e este terminou, devolve a resposta gravada para ser repetida ao cliente.
+func (s *Service) Begin(ctx context.Context, userID, key, requestHash string) (*StoredResponse, error) {
+	now := time.Now().UTC()
+	existing, err := s.repo.Reserve(ctx, &Record{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if existing.Response == nil {
+		return nil, ErrRequestInProgress
+	}
+	return existing.Response, nil
+}
+
+// Finish grava a resposta do pedido. Respostas 5xx libertam a chave, permitindo que o
+// cliente volte a tentar com a mesma chave.
+func (s *Service) Finish(ctx context.Context, userID, key string, resp StoredResponse) error {
+	if resp.StatusCode >= 500 {
+		return s.repo.Release(ctx, userID, key)
+	}
+	return s.repo.Complete(ctx, userID, key, resp)
+}
+
+// Release liberta uma chave cujo pedido não chegou a terminar (ex.: pânico no handler),
+// para que o cliente possa tentar de novo sem esperar que a chave expire.
+func (s *Service) Release(ctx context.Context, userID, key string) error {
+	return s.repo.Release(ctx, userID, key)
+}
+
+func (s *Service) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.repo.DeleteExpired(ctx, now)
+}
@@ -0,0 +1,17 @@
+package invoice
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// Renderer gera o documento de uma fatura para download (ver adapters/pdf).
+type Renderer interface {
+	Render(inv *domain.Invoice, customer *domain.User) ([]byte, error)
+}
+
+// UserRepository fornece os dados do cliente impressos no documento.
+type UserRepository interface {
+	FindUserByID(ctx context.Context, id string) (*domain.User, error)
+}
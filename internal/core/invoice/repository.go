@@ -0,0 +1,14 @@
+package invoice
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// Repository é só de leitura: as faturas são gravadas junto com a assinatura que as originou
+// (ver subscription.Repository.SaveWithInvoice).
+type Repository interface {
+	FindByID(ctx context.Context, id string) (*domain.Invoice, error)
+	ListByUser(ctx context.Context, userID string, filter ListFilter) ([]*domain.Invoice, error)
+}
@@ -0,0 +1,70 @@
+package invoice
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type Service struct {
+	repo     Repository
+	users    UserRepository
+	renderer Renderer
+}
+
+func NewService(repo Repository, users UserRepository, renderer Renderer) *Service {
+	return &Service{repo: repo, users: users, renderer: renderer}
+}
+
+// ListFilter restringe a listagem às faturas de uma assinatura.
+type ListFilter struct {
+	SubscriptionID string
+}
+
+// ListInput são os parâmetros de query de GET /invoices.
+type ListInput struct {
+	SubscriptionID string `json:"subscriptionId"`
+}
+
+// ListResult envolve a lista para manter o formato {"data": [...]} das outras listagens.
+type ListResult struct {
+	Data []*domain.Invoice `json:"data"`
+}
+
+// ListInvoices devolve o histórico de faturas do utilizador, das mais recentes para as mais antigas.
+func (s *Service) ListInvoices(ctx context.Context, userID string, input ListInput) (*ListResult, error) {
+	invoices, err := s.repo.ListByUser(ctx, userID, ListFilter{SubscriptionID: input.SubscriptionID})
+	if err != nil {
+		return nil, err
+	}
+	return &ListResult{Data: invoices}, nil
+}
+
+func (s *Service) GetInvoice(ctx context.Context, userID, invoiceID string) (*domain.Invoice, error) {
+	inv, err := s.repo.FindByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	return inv, nil
+}
+
+// RenderInvoice gera o documento da fatura a cada pedido. As faturas não mudam depois de
+// emitidas, mas o nome e o email do cliente são sempre os atuais.
+func (s *Service) RenderInvoice(ctx context.Context, userID, invoiceID string) (*domain.Invoice, []byte, error) {
+	inv, err := s.GetInvoice(ctx, userID, invoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	customer, err := s.users.FindUserByID(ctx, inv.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := s.renderer.Render(inv, customer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inv, doc, nil
+}
@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, queueName string, body []byte) error
+}
+
+// claimLease é quanto tempo as mensagens reservadas ficam invisíveis para os outros relays.
+// As publicações de um lote têm de terminar dentro deste prazo.
+const claimLease = time.Minute
+
+// Relay publica as mensagens gravadas no outbox. Uma mensagem só é marcada como enviada
+// depois de o broker confirmar a publicação, o que garante entrega at-least-once. As
+// mensagens são reservadas antes de publicar, para que nenhuma transação nem bloqueio
+// fique aberto enquanto se espera pelo broker.
+type Relay struct {
+	repo      Repository
+	publisher Publisher
+	batchSize int
+}
+
+func NewRelay(repo Repository, publisher Publisher, batchSize int) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// RelayPending publica um lote de mensagens pendentes e devolve quantas foram enviadas.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	msgs, err := r.repo.ClaimPending(ctx, r.batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, claimLease)
+	defer cancel()
+
+	sent := 0
+	var errs []error
+	for _, msg := range msgs {
+		if err := r.publisher.Publish(publishCtx, msg.Queue, msg.Payload); err != nil {
+			if err := r.repo.MarkFailed(ctx, msg.ID, err.Error()); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := r.repo.MarkSent(ctx, msg.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Run executa o relay a cada interval até o contexto ser cancelado.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				sent, err := r.RelayPending(ctx)
+				if err != nil {
+					log.Printf("Error relaying outbox messages: %s", err)
+				}
+				if sent < r.batchSize || err != nil {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// memoryRepo guarda as mensagens por ordem de criação; uma mensagem reservada só volta a ser
+// devolvida depois de marcada como falhada.
+type memoryRepo struct {
+	msgs    []*domain.OutboxMessage
+	claimed map[string]bool
+}
+
+func newMemoryRepo(msgs ...*domain.OutboxMessage) *memoryRepo {
+	return &memoryRepo{msgs: msgs, claimed: make(map[string]bool)}
+}
+
+func (r *memoryRepo) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	var claimed []*domain.OutboxMessage
+	for _, msg := range r.msgs {
+		if len(claimed) == limit {
+			break
+		}
+		if msg.SentAt == nil && !r.claimed[msg.ID] {
+			r.claimed[msg.ID] = true
+			claimed = append(claimed, msg)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *memoryRepo) MarkSent(ctx context.Context, id string) error {
+	msg := r.find(id)
+	now := time.Now()
+	msg.Attempts++
+	msg.LastError = nil
+	msg.SentAt = &now
+	delete(r.claimed, id)
+	return nil
+}
+
+func (r *memoryRepo) MarkFailed(ctx context.Context, id string, cause string) error {
+	msg := r.find(id)
+	msg.Attempts++
+	msg.LastError = &cause
+	delete(r.claimed, id)
+	return nil
+}
+
+func (r *memoryRepo) find(id string) *domain.OutboxMessage {
+	for _, msg := range r.msgs {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	panic("unknown outbox message " + id)
+}
+
+// fakePublisher regista as filas publicadas e falha para as filas em failing.
+type fakePublisher struct {
+	published []string
+	failing   map[string]bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, queueName string, body []byte) error {
+	if p.failing[queueName] {
+		return errors.New("broker did not confirm the message")
+	}
+	p.published = append(p.published, queueName)
+	return nil
+}
+
+func outboxMessage(id, queue string) *domain.OutboxMessage {
+	return &domain.OutboxMessage{ID: id, Queue: queue, Payload: []byte(`{}`), CreatedAt: time.Now()}
+}
+
+func TestRelayMarksPublishedMessagesAsSent(t *testing.T) {
+	repo := newMemoryRepo(outboxMessage("msg-1", "created"), outboxMessage("msg-2", "renewed"))
+	publisher := &fakePublisher{}
+
+	sent, err := NewRelay(repo, publisher, 10).RelayPending(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("RelayPending() = %d, %v, want 2 messages sent", sent, err)
+	}
+	if len(publisher.published) != 2 || publisher.published[0] != "created" || publisher.published[1] != "renewed" {
+		t.Errorf("expected both messages to be published in order, got %v", publisher.published)
+	}
+	for _, msg := range repo.msgs {
+		if msg.SentAt == nil || msg.Attempts != 1 {
+			t.Errorf("%s: expected to be sent after one attempt, got sent at %v after %d attempts", msg.ID, msg.SentAt, msg.Attempts)
+		}
+	}
+
+	if sent, err := NewRelay(repo, publisher, 10).RelayPending(context.Background()); err != nil || sent != 0 {
+		t.Errorf("second run: RelayPending() = %d, %v, want nothing left to send", sent, err)
+	}
+}
+
+func TestRelayLeavesFailedMessagesPendingAndCountsAttempts(t *testing.T) {
+	repo := newMemoryRepo(outboxMessage("msg-1", "created"), outboxMessage("msg-2", "renewed"))
+	publisher := &fakePublisher{failing: map[string]bool{"renewed": true}}
+	relay := NewRelay(repo, publisher, 10)
+
+	if sent, err := relay.RelayPending(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("RelayPending() = %d, %v, want 1 message sent", sent, err)
+	}
+	failed := repo.find("msg-2")
+	if failed.SentAt != nil || failed.Attempts != 1 || failed.LastError == nil {
+		t.Fatalf("expected the failed message to stay pending with one attempt and its error, got %+v", failed)
+	}
+
+	publisher.failing = nil
+	if sent, err := relay.RelayPending(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("retry: RelayPending() = %d, %v, want the failed message sent", sent, err)
+	}
+	if failed.SentAt == nil || failed.Attempts != 2 || failed.LastError != nil {
+		t.Errorf("expected the message to be sent on the second attempt, got %+v", failed)
+	}
+	if repo.find("msg-1").Attempts != 1 {
+		t.Errorf("the message sent on the first run must not be published again")
+	}
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type Repository interface {
+	// ClaimPending reserva até limit mensagens pendentes durante lease, numa transação curta,
+	// e devolve-as por ordem de criação. Enquanto a reserva durar nenhum outro relay as vê;
+	// se o relay morrer sem as marcar, voltam a ficar pendentes quando ela expira.
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error)
+	// MarkSent regista a tentativa e marca a mensagem como enviada.
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed regista a tentativa falhada e liberta a reserva para a próxima execução.
+	MarkFailed(ctx context.Context, id string, cause string) error
+}
@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// ErrGatewayUnavailable indica uma falha transitória do provedor (timeout, indisponibilidade).
+// Ao contrário de uma recusa, a mesma cobrança pode ser tentada de novo.
+var ErrGatewayUnavailable = errors.New("payment gateway unavailable")
+
+// ErrChargeNotFound é retornado ao tentar reembolsar uma cobrança desconhecida.
+var ErrChargeNotFound = errors.New("charge not found")
+
+// ErrRefundExceedsCharge é retornado quando o reembolso ultrapassa o valor ainda não reembolsado.
+var ErrRefundExceedsCharge = errors.New("refund amount exceeds the refundable amount of the charge")
+
+// ErrNoPaymentMethod é uma recusa: não há meio de pagamento para cobrar.
+var ErrNoPaymentMethod = fmt.Errorf("%w: subscription has no payment method", domain.ErrPaymentFailed)
+
+// DeclinedError descreve uma cobrança recusada pelo provedor e satisfaz
+// errors.Is(err, domain.ErrPaymentFailed).
+type DeclinedError struct {
+	Code string
+}
+
+func (e *DeclinedError) Error() string {
+	return "payment declined: " + e.Code
+}
+
+func (e *DeclinedError) Is(target error) bool {
+	return target == domain.ErrPaymentFailed
+}
+
+// PaymentGateway é a porta para o provedor de pagamentos. Os valores são sempre em
+// unidades mínimas da moeda, como em domain.Plan.
+type PaymentGateway interface {
+	CreateCustomer(ctx context.Context, req CustomerRequest) (string, error)
+	AttachPaymentMethod(ctx context.Context, customerID, paymentMethodID string) error
+	Charge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
+
+type CustomerRequest struct {
+	UserID string
+	Name   string
+	Email  string
+}
+
+// ChargeRequest descreve uma cobrança. Pedidos repetidos com a mesma IdempotencyKey não
+// geram uma segunda cobrança.
+type ChargeRequest struct {
+	CustomerID      string
+	PaymentMethodID string
+	Amount          int64
+	Currency        string
+	Description     string
+	IdempotencyKey  string
+}
+
+type Charge struct {
+	ID              string    `json:"id"`
+	CustomerID      string    `json:"customerId"`
+	PaymentMethodID string    `json:"paymentMethodId"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// RefundRequest reembolsa Amount da cobrança; zero reembolsa todo o valor restante.
+type RefundRequest struct {
+	ChargeID string
+	Amount   int64
+}
+
+type Refund struct {
+	ID        string    `json:"id"`
+	ChargeID  string    `json:"chargeId"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+}
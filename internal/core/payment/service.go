@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// CustomerRepository guarda a associação entre um utilizador e o cliente no provedor.
+type CustomerRepository interface {
+	FindUserByID(ctx context.Context, id string) (*domain.User, error)
+	SetPaymentCustomerID(ctx context.Context, userID, customerID string) error
+}
+
+// Service cobra as assinaturas através do PaymentGateway configurado.
+type Service struct {
+	gateway   PaymentGateway
+	customers CustomerRepository
+}
+
+func NewService(gateway PaymentGateway, customers CustomerRepository) *Service {
+	return &Service{
+		gateway:   gateway,
+		customers: customers,
+	}
+}
+
+// CollectPayment cobra o valor devido da fatura no meio de pagamento da assinatura e marca-a
+// como paga. Faturas sem valor a cobrar (ex: créditos de downgrade) são apenas marcadas como pagas.
+// A chave de idempotência é a fatura: cada fatura é cobrada no máximo uma vez, e cobranças de
+// faturas diferentes (outro motivo, período ou valor) nunca reaproveitam uma cobrança anterior.
+func (s *Service) CollectPayment(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice) error {
+	if inv.AmountDue() == 0 {
+		inv.MarkPaid("", time.Now().UTC())
+		return nil
+	}
+	if !sub.HasPaymentMethod() {
+		return ErrNoPaymentMethod
+	}
+
+	customerID, err := s.customerFor(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+
+	charge, err := s.gateway.Charge(ctx, ChargeRequest{
+		CustomerID:      customerID,
+		PaymentMethodID: *sub.PaymentMethodID,
+		Amount:          inv.AmountDue(),
+		Currency:        inv.Currency,
+		Description:     fmt.Sprintf("Subscription %s - %s", sub.ID, inv.Reason),
+		IdempotencyKey:  "invoice:" + inv.ID,
+	})
+	if err != nil {
+		return err
+	}
+	inv.MarkPaid(charge.ID, charge.CreatedAt)
+	return nil
+}
+
+// AttachPaymentMethod associa o meio de pagamento ao cliente do utilizador no provedor,
+// criando o cliente na primeira utilização.
+func (s *Service) AttachPaymentMethod(ctx context.Context, userID, paymentMethodID string) error {
+	customerID, err := s.customerFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.gateway.AttachPaymentMethod(ctx, customerID, paymentMethodID)
+}
+
+// Refund devolve amount (ou o valor restante, se zero) de uma cobrança.
+func (s *Service) Refund(ctx context.Context, chargeID string, amount int64) (*Refund, error) {
+	return s.gateway.Refund(ctx, RefundRequest{ChargeID: chargeID, Amount: amount})
+}
+
+// RefundInvoice reembolsa todo o valor cobrado pela fatura. Faturas sem cobrança são ignoradas.
+func (s *Service) RefundInvoice(ctx context.Context, inv *domain.Invoice) error {
+	if inv.ChargeID == nil {
+		return nil
+	}
+	_, err := s.Refund(ctx, *inv.ChargeID, 0)
+	return err
+}
+
+func (s *Service) customerFor(ctx context.Context, userID string) (string, error) {
+	user, err := s.customers.FindUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.PaymentCustomerID != nil {
+		return *user.PaymentCustomerID, nil
+	}
+
+	customerID, err := s.gateway.CreateCustomer(ctx, CustomerRequest{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.customers.SetPaymentCustomerID(ctx, user.ID, customerID); err != nil {
+		return "", err
+	}
+	return customerID, nil
+}
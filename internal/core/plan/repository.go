@@ -0,0 +1,13 @@
+package plan
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type Repository interface {
+	Save(ctx context.Context, p *domain.Plan) error
+	FindByID(ctx context.Context, id string) (*domain.Plan, error)
+	List(ctx context.Context, includeInactive bool) ([]*domain.Plan, error)
+}
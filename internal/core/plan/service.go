@@ -0,0 +1,96 @@
+package plan
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type PlanInput struct {
+	Name         string                 `json:"name" validate:"required,min=2"`
+	Price        int64                  `json:"price" validate:"gte=0"`
+	Currency     string                 `json:"currency" validate:"required,len=3"`
+	Interval     domain.BillingInterval `json:"interval" validate:"required,oneof=MONTHLY YEARLY CUSTOM"`
+	IntervalDays int                    `json:"intervalDays" validate:"required_if=Interval CUSTOM,gte=0"`
+	TrialDays    int                    `json:"trialDays" validate:"gte=0"`
+	// Family é opcional; sem ela o plano forma uma família própria.
+	Family string `json:"family" validate:"max=255"`
+}
+
+func (s *Service) CreatePlan(ctx context.Context, input PlanInput) (*domain.Plan, error) {
+	now := time.Now().UTC()
+	id := uuid.NewString()
+	family := input.Family
+	if family == "" {
+		family = id
+	}
+	p := &domain.Plan{
+		ID:           id,
+		Name:         input.Name,
+		Price:        input.Price,
+		Currency:     strings.ToUpper(input.Currency),
+		Interval:     input.Interval,
+		IntervalDays: input.IntervalDays,
+		TrialDays:    input.TrialDays,
+		Family:       family,
+		Active:       true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) GetPlan(ctx context.Context, id string) (*domain.Plan, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *Service) ListPlans(ctx context.Context, includeInactive bool) ([]*domain.Plan, error) {
+	return s.repo.List(ctx, includeInactive)
+}
+
+func (s *Service) UpdatePlan(ctx context.Context, id string, input PlanInput) (*domain.Plan, error) {
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Name = input.Name
+	p.Price = input.Price
+	p.Currency = strings.ToUpper(input.Currency)
+	p.Interval = input.Interval
+	p.IntervalDays = input.IntervalDays
+	p.TrialDays = input.TrialDays
+	if input.Family != "" {
+		p.Family = input.Family
+	}
+	p.UpdatedAt = time.Now().UTC()
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) ArchivePlan(ctx context.Context, id string) error {
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	p.Archive()
+	return s.repo.Save(ctx, p)
+}
@@ -0,0 +1,79 @@
+package subscription
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// Os casos de uso abaixo são reservados a administradores e, por isso, não verificam
+// se a assinatura pertence ao utilizador autenticado.
+
+// AdminListSubscriptions lista as assinaturas de todos os utilizadores, ou apenas as de
+// userID quando informado.
+func (s *Service) AdminListSubscriptions(ctx context.Context, userID string, input ListInput) (*ListResult, error) {
+	filter, err := input.toFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := filter.Limit
+	filter.Limit++
+
+	var subs []*domain.Subscription
+	if userID != "" {
+		subs, err = s.repo.ListByUser(ctx, userID, filter)
+	} else {
+		subs, err = s.repo.ListAll(ctx, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newListResult(subs, pageSize), nil
+}
+
+func (s *Service) ForceCancelSubscription(ctx context.Context, subscriptionID string, expectedVersion int) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	if !sub.CanBeCancelled() {
+		return nil, domain.ErrSubscriptionCannotBeCancelled
+	}
+	if err := sub.Cancel(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) ForceReactivateSubscription(ctx context.Context, subscriptionID string, expectedVersion int) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	p, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	// A reativação inicia um novo período pago; se a cobrança falhar nada é gravado.
+	if err := sub.Reactivate(p); err != nil {
+		return nil, err
+	}
+	inv := s.periodInvoice(sub, p, domain.InvoiceReasonActivation, *sub.CurrentPeriodStart)
+	if err := s.collectPeriodPayment(ctx, sub, inv); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveWithInvoice(ctx, sub, inv); err != nil {
+		return nil, s.refundUnsaved(ctx, inv, err)
+	}
+	return sub, nil
+}
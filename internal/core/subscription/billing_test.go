@@ -0,0 +1,527 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/adapters/gateway"
+	"github.com/manuzokas/subscription-api/internal/core/coupon"
+	"github.com/manuzokas/subscription-api/internal/core/payment"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// memoryRepo implementa apenas os métodos usados pelos fluxos de cobrança; os restantes
+// métodos de Repository entram em pânico via a interface nil embutida.
+type memoryRepo struct {
+	Repository
+	subs        map[string]*domain.Subscription
+	events      []*domain.OutboxMessage
+	invoices    []*domain.Invoice
+	redemptions []*domain.CouponRedemption
+	// invoiceErr simula uma falha ao gravar a fatura depois de a cobrança ter sido feita.
+	invoiceErr error
+}
+
+func newMemoryRepo(subs ...*domain.Subscription) *memoryRepo {
+	r := &memoryRepo{subs: make(map[string]*domain.Subscription)}
+	for _, sub := range subs {
+		sub.Version = 1
+		r.subs[sub.ID] = sub
+	}
+	return r
+}
+
+func (r *memoryRepo) Save(_ context.Context, sub *domain.Subscription) error {
+	if stored, ok := r.subs[sub.ID]; ok && stored.Version != sub.Version {
+		return &domain.VersionConflictError{SubscriptionID: sub.ID, Version: sub.Version}
+	}
+	sub.Version++
+	copied := *sub
+	r.subs[sub.ID] = &copied
+	return nil
+}
+
+func (r *memoryRepo) SaveWithEvents(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error {
+	if err := r.Save(ctx, sub); err != nil {
+		return err
+	}
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *memoryRepo) SaveWithInvoice(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice, events ...*domain.OutboxMessage) error {
+	if r.invoiceErr != nil {
+		return r.invoiceErr
+	}
+	if err := r.SaveWithEvents(ctx, sub, events...); err != nil {
+		return err
+	}
+	inv.Number = domain.FormatInvoiceNumber(int64(len(r.invoices) + 1))
+	r.invoices = append(r.invoices, inv)
+	return nil
+}
+
+func (r *memoryRepo) SaveReleasingRedemption(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error {
+	if err := r.SaveWithEvents(ctx, sub, events...); err != nil {
+		return err
+	}
+	kept := r.redemptions[:0]
+	for _, redemption := range r.redemptions {
+		if redemption.SubscriptionID != sub.ID {
+			kept = append(kept, redemption)
+		}
+	}
+	r.redemptions = kept
+	return nil
+}
+
+func (r *memoryRepo) SaveWithRedemption(ctx context.Context, sub *domain.Subscription, redemption *domain.CouponRedemption, events ...*domain.OutboxMessage) error {
+	if err := r.SaveWithEvents(ctx, sub, events...); err != nil {
+		return err
+	}
+	r.redemptions = append(r.redemptions, redemption)
+	return nil
+}
+
+func (r *memoryRepo) FindByID(_ context.Context, id string) (*domain.Subscription, error) {
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+func (r *memoryRepo) find(match func(*domain.Subscription) bool) []*domain.Subscription {
+	var found []*domain.Subscription
+	for _, sub := range r.subs {
+		if match(sub) {
+			copied := *sub
+			found = append(found, &copied)
+		}
+	}
+	return found
+}
+
+func (r *memoryRepo) FindDueForRenewal(_ context.Context, now time.Time, _ int) ([]*domain.Subscription, error) {
+	return r.find(func(s *domain.Subscription) bool { return s.IsDueForRenewal(now) }), nil
+}
+
+func (r *memoryRepo) FindTrialsEndedBefore(_ context.Context, now time.Time, _ int) ([]*domain.Subscription, error) {
+	return r.find(func(s *domain.Subscription) bool {
+		return s.Status == domain.StatusTrial && !s.TrialEndsAt.After(now)
+	}), nil
+}
+
+func (r *memoryRepo) FindDunningDue(_ context.Context, now time.Time, _ int) ([]*domain.Subscription, error) {
+	return r.find(func(s *domain.Subscription) bool {
+		return s.Status == domain.StatusPastDue && s.NextDunningAt != nil && !s.NextDunningAt.After(now)
+	}), nil
+}
+
+func (r *memoryRepo) HasHadTrial(_ context.Context, userID, planFamily string) (bool, error) {
+	found := r.find(func(s *domain.Subscription) bool {
+		return s.UserID == userID && s.TrialPlanFamily != nil && *s.TrialPlanFamily == planFamily
+	})
+	return len(found) > 0, nil
+}
+
+func (r *memoryRepo) queued(queue string) [][]byte {
+	var payloads [][]byte
+	for _, e := range r.events {
+		if e.Queue == queue {
+			payloads = append(payloads, e.Payload)
+		}
+	}
+	return payloads
+}
+
+type memoryUsers struct {
+	users map[string]*domain.User
+}
+
+func (m *memoryUsers) CreateUser(_ context.Context, user *domain.User) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *memoryUsers) FindUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memoryUsers) FindUserByID(_ context.Context, id string) (*domain.User, error) {
+	if u, ok := m.users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memoryUsers) SetPaymentCustomerID(_ context.Context, userID, customerID string) error {
+	m.users[userID].PaymentCustomerID = &customerID
+	return nil
+}
+
+type memoryPlans struct {
+	plans map[string]*domain.Plan
+}
+
+func (m *memoryPlans) Save(_ context.Context, p *domain.Plan) error {
+	m.plans[p.ID] = p
+	return nil
+}
+
+func (m *memoryPlans) FindByID(_ context.Context, id string) (*domain.Plan, error) {
+	if p, ok := m.plans[id]; ok {
+		return p, nil
+	}
+	return nil, domain.ErrPlanNotFound
+}
+
+func (m *memoryPlans) List(context.Context, bool) ([]*domain.Plan, error) {
+	return nil, nil
+}
+
+// memoryCoupons consulta os resgates gravados em memoryRepo.
+type memoryCoupons struct {
+	coupon.Repository
+	coupons map[string]*domain.Coupon
+	repo    *memoryRepo
+}
+
+func (m *memoryCoupons) FindByCode(_ context.Context, code string) (*domain.Coupon, error) {
+	for _, c := range m.coupons {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return nil, domain.ErrCouponNotFound
+}
+
+func (m *memoryCoupons) HasRedeemed(_ context.Context, couponID, userID string) (bool, error) {
+	for _, r := range m.repo.redemptions {
+		if r.CouponID == couponID && r.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type billingFixture struct {
+	repo    *memoryRepo
+	coupons *memoryCoupons
+	gateway *gateway.FakeGateway
+	service *Service
+	plan    *domain.Plan
+}
+
+func newBillingFixture(subs ...*domain.Subscription) *billingFixture {
+	monthly := &domain.Plan{ID: "monthly", Name: "Mensal", Price: 2990, Currency: "BRL", Interval: domain.BillingIntervalMonthly, Active: true}
+	users := &memoryUsers{users: map[string]*domain.User{
+		"user-1": {ID: "user-1", Name: "Ana", Email: "ana@example.com"},
+	}}
+	fake := gateway.NewFakeGateway()
+	repo := newMemoryRepo(subs...)
+	coupons := &memoryCoupons{coupons: make(map[string]*domain.Coupon), repo: repo}
+	plans := &memoryPlans{plans: map[string]*domain.Plan{monthly.ID: monthly}}
+	return &billingFixture{
+		repo:    repo,
+		coupons: coupons,
+		gateway: fake,
+		service: NewService(repo, users, plans, coupons, payment.NewService(fake, users), BillingConfig{TaxRateBasisPoints: 1000}),
+		plan:    monthly,
+	}
+}
+
+func activeSubscription(periodEnd time.Time) *domain.Subscription {
+	pm := "pm_visa"
+	sub := &domain.Subscription{ID: "sub-1", UserID: "user-1", PlanID: "monthly", Status: domain.StatusActive, PaymentMethodID: &pm}
+	sub.StartBillingPeriod(periodEnd.AddDate(0, -1, 0), periodEnd)
+	return sub
+}
+
+var testSchedule = domain.DunningScheduleFromDays([]int{1, 3})
+
+func TestRenewalChargesThePlan(t *testing.T) {
+	periodEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	f := newBillingFixture(activeSubscription(periodEnd))
+
+	renewed, err := f.service.RenewDueSubscriptions(context.Background(), periodEnd, testSchedule, 10)
+	if err != nil || renewed != 1 {
+		t.Fatalf("RenewDueSubscriptions() = %d, %v", renewed, err)
+	}
+
+	charges := f.gateway.Charges()
+	if len(charges) != 1 || charges[0].Amount != 3289 || charges[0].CustomerID != "cus_user-1" {
+		t.Fatalf("unexpected charges %+v", charges)
+	}
+	if len(f.repo.invoices) != 1 {
+		t.Fatalf("expected one invoice, got %d", len(f.repo.invoices))
+	}
+	inv := f.repo.invoices[0]
+	if inv.Reason != domain.InvoiceReasonRenewal || inv.Subtotal != f.plan.Price || inv.Tax != 299 || inv.Total != 3289 {
+		t.Errorf("unexpected invoice totals %+v", inv)
+	}
+	if inv.ChargeID == nil || *inv.ChargeID != charges[0].ID || !inv.PeriodStart.Equal(periodEnd) {
+		t.Errorf("invoice not linked to the charge or period: %+v", inv)
+	}
+	sub := f.repo.subs["sub-1"]
+	if !sub.CurrentPeriodEnd.Equal(periodEnd.AddDate(0, 1, 0)) {
+		t.Errorf("period end = %s, want one month later", sub.CurrentPeriodEnd)
+	}
+	if len(f.repo.queued(QueueSubscriptionRenewed)) != 1 {
+		t.Error("expected a renewal event")
+	}
+}
+
+func TestRenewalTimeoutIsRetriedWithoutDoubleCharging(t *testing.T) {
+	periodEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	f := newBillingFixture(activeSubscription(periodEnd))
+	f.gateway.Script(gateway.OutcomeTimeout)
+
+	if _, err := f.service.RenewDueSubscriptions(context.Background(), periodEnd, testSchedule, 10); !errors.Is(err, payment.ErrGatewayUnavailable) {
+		t.Fatalf("expected gateway error, got %v", err)
+	}
+	if sub := f.repo.subs["sub-1"]; sub.Status != domain.StatusActive || !sub.CurrentPeriodEnd.Equal(periodEnd) {
+		t.Fatalf("subscription should be untouched after a timeout, got %s until %s", sub.Status, sub.CurrentPeriodEnd)
+	}
+
+	if renewed, err := f.service.RenewDueSubscriptions(context.Background(), periodEnd, testSchedule, 10); err != nil || renewed != 1 {
+		t.Fatalf("retry: RenewDueSubscriptions() = %d, %v", renewed, err)
+	}
+	if len(f.gateway.Charges()) != 1 {
+		t.Errorf("expected exactly one charge, got %d", len(f.gateway.Charges()))
+	}
+}
+
+func TestDeclinedRenewalGoesThroughDunningUntilCancelled(t *testing.T) {
+	ctx := context.Background()
+	periodEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	f := newBillingFixture(activeSubscription(periodEnd))
+	f.gateway.Script(gateway.OutcomeDecline, gateway.OutcomeDecline, gateway.OutcomeDecline)
+
+	if _, err := f.service.RenewDueSubscriptions(ctx, periodEnd, testSchedule, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub := f.repo.subs["sub-1"]; sub.Status != domain.StatusPastDue {
+		t.Fatalf("status = %s, want PAST_DUE", sub.Status)
+	}
+
+	for _, day := range []int{1, 3} {
+		if _, err := f.service.RetryPastDueSubscriptions(ctx, periodEnd.AddDate(0, 0, day), testSchedule, 10); err != nil {
+			t.Fatalf("day %d: unexpected error: %v", day, err)
+		}
+	}
+
+	if sub := f.repo.subs["sub-1"]; sub.Status != domain.StatusCancelled {
+		t.Fatalf("status = %s, want CANCELLED after the last attempt", sub.Status)
+	}
+	notifications := f.repo.queued(QueuePaymentFailed)
+	if len(notifications) != 3 {
+		t.Fatalf("expected 3 payment failed events, got %d", len(notifications))
+	}
+	var last PaymentFailedEvent
+	if err := json.Unmarshal(notifications[2], &last); err != nil {
+		t.Fatal(err)
+	}
+	if !last.Final || last.Attempt != 2 || last.NextAttemptAt != nil {
+		t.Errorf("unexpected final event %+v", last)
+	}
+	if len(f.gateway.Charges()) != 0 || len(f.repo.invoices) != 0 {
+		t.Errorf("expected no charges or invoices, got %d charges and %d invoices", len(f.gateway.Charges()), len(f.repo.invoices))
+	}
+}
+
+func TestDunningRecoversWhenPaymentSucceeds(t *testing.T) {
+	ctx := context.Background()
+	periodEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	f := newBillingFixture(activeSubscription(periodEnd))
+	f.gateway.Script(gateway.OutcomeDecline)
+
+	if _, err := f.service.RenewDueSubscriptions(ctx, periodEnd, testSchedule, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recovered, err := f.service.RetryPastDueSubscriptions(ctx, periodEnd.AddDate(0, 0, 1), testSchedule, 10)
+	if err != nil || recovered != 1 {
+		t.Fatalf("RetryPastDueSubscriptions() = %d, %v", recovered, err)
+	}
+
+	sub := f.repo.subs["sub-1"]
+	if sub.Status != domain.StatusActive || sub.PastDueSince != nil {
+		t.Fatalf("expected recovered active subscription, got %s", sub.Status)
+	}
+	if !sub.CurrentPeriodStart.Equal(periodEnd) {
+		t.Errorf("period start = %s, want the original billing date %s", sub.CurrentPeriodStart, periodEnd)
+	}
+	if len(f.gateway.Charges()) != 1 {
+		t.Errorf("expected one charge, got %d", len(f.gateway.Charges()))
+	}
+}
+
+func TestTrialWithDeclinedPaymentExpires(t *testing.T) {
+	trialEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	pm := gateway.PaymentMethodDeclined
+	sub := &domain.Subscription{ID: "sub-1", UserID: "user-1", PlanID: "monthly", Status: domain.StatusTrial, TrialEndsAt: &trialEnd, PaymentMethodID: &pm}
+	f := newBillingFixture(sub)
+
+	if _, err := f.service.ExpireTrials(context.Background(), trialEnd, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := f.repo.subs["sub-1"].Status; got != domain.StatusExpired {
+		t.Errorf("status = %s, want EXPIRED", got)
+	}
+}
+
+func TestImmediateUpgradeChargesAndInvoicesTheProration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	sub := activeSubscription(now.AddDate(0, 0, 15))
+	sub.StartBillingPeriod(now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+	f := newBillingFixture(sub)
+	pro := &domain.Plan{ID: "pro", Name: "Pro", Price: 5990, Currency: "BRL", Interval: domain.BillingIntervalMonthly, Active: true}
+	f.service.planRepo.(*memoryPlans).plans[pro.ID] = pro
+
+	result, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: pro.ID, Mode: domain.PlanChangeImmediately})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Invoice == nil || result.Invoice.Reason != domain.InvoiceReasonPlanChange || len(result.Invoice.Lines) != 3 {
+		t.Fatalf("expected a plan change invoice with credit, charge and tax lines, got %+v", result.Invoice)
+	}
+	charges := f.gateway.Charges()
+	if len(charges) != 1 || charges[0].Amount != result.Invoice.Total {
+		t.Errorf("expected one charge of %d, got %+v", result.Invoice.Total, charges)
+	}
+	if f.repo.subs["sub-1"].PlanID != pro.ID {
+		t.Errorf("plan = %s, want %s", f.repo.subs["sub-1"].PlanID, pro.ID)
+	}
+}
+
+func TestChargeIsRefundedWhenTheInvoiceIsNotSaved(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	sub := activeSubscription(now.AddDate(0, 0, 15))
+	sub.StartBillingPeriod(now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+	f := newBillingFixture(sub)
+	pro := &domain.Plan{ID: "pro", Name: "Pro", Price: 5990, Currency: "BRL", Interval: domain.BillingIntervalMonthly, Active: true}
+	f.service.planRepo.(*memoryPlans).plans[pro.ID] = pro
+
+	f.repo.invoiceErr = &domain.VersionConflictError{SubscriptionID: "sub-1", Version: 1}
+	_, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: pro.ID, Mode: domain.PlanChangeImmediately})
+	var conflict *domain.VersionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	first := f.gateway.Charges()
+	if len(first) != 1 || f.gateway.Refunded(first[0].ID) != first[0].Amount {
+		t.Fatalf("expected the unsaved charge to be fully refunded, got %+v", first)
+	}
+
+	// A repetição gera uma nova fatura e portanto uma nova cobrança, que fica por reembolsar.
+	f.repo.invoiceErr = nil
+	result, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: pro.ID, Mode: domain.PlanChangeImmediately})
+	if err != nil {
+		t.Fatalf("retry: unexpected error: %v", err)
+	}
+	charges := f.gateway.Charges()
+	if len(charges) != 2 || charges[1].ID != *result.Invoice.ChargeID || f.gateway.Refunded(charges[1].ID) != 0 {
+		t.Errorf("expected a second, unrefunded charge for the saved invoice, got %+v", charges)
+	}
+}
+
+func TestImmediateDowngradeCreditIsAppliedToTheNextRenewal(t *testing.T) {
+	ctx := context.Background()
+	periodEnd := time.Now().UTC().AddDate(0, 0, 15)
+	sub := activeSubscription(periodEnd)
+	sub.PlanID = "pro"
+	sub.StartBillingPeriod(periodEnd.AddDate(0, 0, -30), periodEnd)
+	f := newBillingFixture(sub)
+	pro := &domain.Plan{ID: "pro", Name: "Pro", Price: 5990, Currency: "BRL", Interval: domain.BillingIntervalMonthly, Active: true}
+	f.service.planRepo.(*memoryPlans).plans[pro.ID] = pro
+
+	result, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: f.plan.ID, Mode: domain.PlanChangeImmediately})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	credit := result.Proration.Credit - result.Proration.Charge
+	if credit <= 0 || result.Invoice.Total != 0 || len(f.gateway.Charges()) != 0 {
+		t.Fatalf("expected a zero invoice and no charge for the downgrade, got %+v", result.Invoice)
+	}
+	if balance := f.repo.subs["sub-1"].CreditBalance; balance != credit {
+		t.Fatalf("credit balance = %d, want %d", balance, credit)
+	}
+
+	if _, err := f.service.RenewDueSubscriptions(ctx, periodEnd, testSchedule, 10); err != nil {
+		t.Fatalf("unexpected renewal error: %v", err)
+	}
+	renewal := f.repo.invoices[len(f.repo.invoices)-1]
+	if renewal.Reason != domain.InvoiceReasonRenewal || renewal.CreditApplied() != credit || renewal.Total != 3289-credit {
+		t.Fatalf("expected the renewal to use the %d credit, got %+v", credit, renewal)
+	}
+	charges := f.gateway.Charges()
+	if len(charges) != 1 || charges[0].Amount != 3289-credit {
+		t.Errorf("expected one charge of %d, got %+v", 3289-credit, charges)
+	}
+	if balance := f.repo.subs["sub-1"].CreditBalance; balance != 0 {
+		t.Errorf("credit balance after renewal = %d, want 0", balance)
+	}
+}
+
+func TestImmediateUpgradeAppliesPercentDiscount(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	sub := activeSubscription(now.AddDate(0, 0, 15))
+	sub.StartBillingPeriod(now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+	sub.Discount = &domain.Discount{Code: "HALF", PercentOff: 50, Duration: domain.CouponDurationForever}
+	f := newBillingFixture(sub)
+	pro := &domain.Plan{ID: "pro", Name: "Pro", Price: 5990, Currency: "BRL", Interval: domain.BillingIntervalMonthly, Active: true}
+	f.service.planRepo.(*memoryPlans).plans[pro.ID] = pro
+
+	result, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: pro.ID, Mode: domain.PlanChangeImmediately})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	net := result.Proration.Charge - result.Proration.Credit
+	if result.Invoice.Subtotal != net-net*50/100 {
+		t.Errorf("subtotal = %d, want the net proration %d with 50%% off", result.Invoice.Subtotal, net)
+	}
+}
+
+func TestCycleChangingUpgradeAppliesAmountOffDiscountToTheNewPeriod(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	sub := activeSubscription(now.AddDate(0, 0, 15))
+	sub.StartBillingPeriod(now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+	sub.Discount = &domain.Discount{Code: "OFF10", AmountOff: 1000, Currency: "BRL", Duration: domain.CouponDurationRepeating, RemainingPeriods: 2}
+	f := newBillingFixture(sub)
+	yearly := &domain.Plan{ID: "yearly", Name: "Anual", Price: 29900, Currency: "BRL", Interval: domain.BillingIntervalYearly, Active: true}
+	f.service.planRepo.(*memoryPlans).plans[yearly.ID] = yearly
+
+	result, err := f.service.ChangePlan(ctx, "user-1", "sub-1", AnyVersion, ChangePlanInput{PlanID: yearly.ID, Mode: domain.PlanChangeImmediately})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var discount int64
+	for _, line := range result.Invoice.Lines {
+		if line.Type == domain.InvoiceLineDiscount {
+			discount += line.Amount
+		}
+	}
+	if discount != -1000 {
+		t.Errorf("discount = %d, want the 1000 off on the new yearly period", discount)
+	}
+	if want := result.Proration.Charge - result.Proration.Credit - 1000; result.Invoice.Subtotal != want {
+		t.Errorf("subtotal = %d, want %d", result.Invoice.Subtotal, want)
+	}
+	saved := f.repo.subs["sub-1"]
+	if saved.Discount == nil || saved.Discount.RemainingPeriods != 1 {
+		t.Errorf("expected the new period to consume one coupon period, got %+v", saved.Discount)
+	}
+}
@@ -0,0 +1,122 @@
+package subscription
+
+import (
+	"context"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+type ChangePlanInput struct {
+	PlanID string                `json:"planId" validate:"required"`
+	Mode   domain.PlanChangeMode `json:"mode" validate:"required,oneof=immediately at_period_end"`
+}
+
+// ChangePlanResult descreve a troca efetuada. Proration só é preenchido em trocas imediatas
+// e Invoice apenas quando a troca imediata foi faturada (assinaturas ativas).
+type ChangePlanResult struct {
+	Subscription *domain.Subscription  `json:"subscription"`
+	Mode         domain.PlanChangeMode `json:"mode"`
+	EffectiveAt  time.Time             `json:"effectiveAt"`
+	Proration    *domain.Proration     `json:"proration,omitempty"`
+	Invoice      *domain.Invoice       `json:"invoice,omitempty"`
+}
+
+// ChangePlan troca o plano de uma assinatura imediatamente, com rateio do período restante
+// cobrado na hora, ou agenda a troca para o fim do período corrente. Pedir o plano atual desfaz
+// uma troca agendada.
+func (s *Service) ChangePlan(ctx context.Context, userID, subscriptionID string, expectedVersion int, input ChangePlanInput) (*ChangePlanResult, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	if !sub.CanChangePlan() {
+		return nil, domain.ErrPlanChangeNotAllowed
+	}
+
+	now := time.Now().UTC()
+
+	if input.PlanID == sub.PlanID {
+		if sub.PendingPlanID == nil {
+			return nil, domain.ErrPlanUnchanged
+		}
+		sub.ClearPendingPlanChange(now)
+		if err := s.repo.Save(ctx, sub); err != nil {
+			return nil, err
+		}
+		return &ChangePlanResult{Subscription: sub, Mode: input.Mode, EffectiveAt: now}, nil
+	}
+
+	current, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := s.planRepo.FindByID(ctx, input.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if !next.Active {
+		return nil, domain.ErrPlanInactive
+	}
+	if current.Currency != next.Currency {
+		return nil, domain.ErrPlanCurrencyMismatch
+	}
+
+	result := &ChangePlanResult{Subscription: sub, Mode: input.Mode}
+	var inv *domain.Invoice
+	switch input.Mode {
+	case domain.PlanChangeAtPeriodEnd:
+		if err := sub.SchedulePlanChange(next.ID, now); err != nil {
+			return nil, err
+		}
+		result.EffectiveAt = *sub.CurrentPeriodEnd
+	default:
+		// Durante o trial ainda nada foi cobrado, por isso não há valores a ratear nem a faturar.
+		proration := domain.Proration{Currency: next.Currency}
+		if sub.Status == domain.StatusActive {
+			proration, err = domain.CalculateProration(current, next, *sub.CurrentPeriodStart, *sub.CurrentPeriodEnd, now)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := sub.ChangePlan(current, next, now); err != nil {
+			return nil, err
+		}
+		if sub.Status == domain.StatusActive {
+			inv = s.planChangeInvoice(sub, current, next, proration, now)
+			if err := s.collectPlanChangePayment(ctx, sub, inv, !current.SameBillingCycle(next)); err != nil {
+				return nil, err
+			}
+		}
+		result.EffectiveAt = now
+		result.Proration = &proration
+	}
+
+	event, err := domain.NewOutboxMessage(QueuePlanChanged, PlanChangedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		PreviousPlanID: current.ID,
+		NewPlanID:      next.ID,
+		Mode:           result.Mode,
+		EffectiveAt:    result.EffectiveAt,
+		Proration:      result.Proration,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if inv != nil {
+		if err := s.repo.SaveWithInvoice(ctx, sub, inv, event); err != nil {
+			return nil, s.refundUnsaved(ctx, inv, err)
+		}
+	} else if err := s.repo.SaveWithEvents(ctx, sub, event); err != nil {
+		return nil, err
+	}
+	result.Invoice = inv
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package subscription
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manuzokas/subscription-api/internal/core/coupon"
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// redeemCoupon valida o cupom para o plano p e aplica o desconto à nova assinatura. O resgate
+// só conta quando a assinatura é gravada (ver Repository.SaveWithRedemption).
+func (s *Service) redeemCoupon(ctx context.Context, sub *domain.Subscription, p *domain.Plan, code string, now time.Time) (*domain.CouponRedemption, error) {
+	c, err := s.coupons.FindByCode(ctx, coupon.NormalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CheckRedeemable(p, now); err != nil {
+		return nil, err
+	}
+	redeemed, err := s.coupons.HasRedeemed(ctx, c.ID, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if redeemed {
+		return nil, domain.ErrCouponAlreadyRedeemed
+	}
+
+	sub.Discount = c.NewDiscount()
+	return &domain.CouponRedemption{
+		ID:             uuid.NewString(),
+		CouponID:       c.ID,
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		CreatedAt:      now,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func TestCreateSubscriptionRedeemsCouponOncePerUser(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture()
+	f.coupons.coupons["c1"] = &domain.Coupon{ID: "c1", Code: "WELCOME", PercentOff: 20, Duration: domain.CouponDurationOnce, Active: true}
+
+	sub, err := f.service.CreateSubscription(ctx, "user-1", CreateSubscriptionInput{PlanID: f.plan.ID, CouponCode: " welcome "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Discount == nil || sub.Discount.CouponID != "c1" || sub.Discount.RemainingPeriods != 1 {
+		t.Errorf("unexpected discount %+v", sub.Discount)
+	}
+	if len(f.repo.redemptions) != 1 || f.repo.redemptions[0].SubscriptionID != sub.ID {
+		t.Fatalf("expected the redemption to be saved with the subscription, got %+v", f.repo.redemptions)
+	}
+
+	_, err = f.service.CreateSubscription(ctx, "user-1", CreateSubscriptionInput{PlanID: f.plan.ID, CouponCode: "WELCOME"})
+	if !errors.Is(err, domain.ErrCouponAlreadyRedeemed) {
+		t.Errorf("second redemption error = %v, want ErrCouponAlreadyRedeemed", err)
+	}
+}
+
+func TestRepeatingDiscountAppliesToTheConfiguredRenewals(t *testing.T) {
+	ctx := context.Background()
+	periodEnd := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	sub := activeSubscription(periodEnd)
+	twoMonths := &domain.Coupon{ID: "c1", Code: "HALF", PercentOff: 50, Duration: domain.CouponDurationRepeating, DurationInPeriods: 2}
+	sub.Discount = twoMonths.NewDiscount()
+	f := newBillingFixture(sub)
+
+	now := periodEnd
+	for i := 0; i < 3; i++ {
+		if _, err := f.service.RenewDueSubscriptions(ctx, now, testSchedule, 10); err != nil {
+			t.Fatalf("renewal %d: %v", i+1, err)
+		}
+		now = *f.repo.subs["sub-1"].CurrentPeriodEnd
+	}
+
+	// 2990 com 50% de desconto e 10% de imposto, duas vezes; depois o preço cheio.
+	want := []int64{1645, 1645, 3289}
+	if len(f.repo.invoices) != len(want) {
+		t.Fatalf("expected %d invoices, got %d", len(want), len(f.repo.invoices))
+	}
+	for i, inv := range f.repo.invoices {
+		if inv.Total != want[i] {
+			t.Errorf("invoice %d total = %d, want %d", i+1, inv.Total, want[i])
+		}
+	}
+	if f.repo.subs["sub-1"].Discount != nil {
+		t.Errorf("expected the discount to run out, got %+v", f.repo.subs["sub-1"].Discount)
+	}
+}
@@ -0,0 +1,116 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// RetryPastDueSubscriptions volta a cobrar as assinaturas em PAST_DUE cuja próxima tentativa
+// já chegou. Cobranças bem-sucedidas reativam a assinatura; depois da última tentativa falhada
+// da cadência a assinatura é cancelada. Devolve o número de assinaturas recuperadas.
+func (s *Service) RetryPastDueSubscriptions(ctx context.Context, now time.Time, schedule domain.DunningSchedule, batchSize int) (int, error) {
+	subs, err := s.repo.FindDunningDue(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	plans := make(map[string]*domain.Plan)
+	recovered := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.retryPastDue(ctx, sub, plans, now, schedule); err != nil {
+			errs = append(errs, fmt.Errorf("retrying payment of subscription %s: %w", sub.ID, err))
+			continue
+		}
+		if sub.Status == domain.StatusActive {
+			recovered++
+		}
+	}
+	return recovered, errors.Join(errs...)
+}
+
+func (s *Service) retryPastDue(ctx context.Context, sub *domain.Subscription, plans map[string]*domain.Plan, now time.Time, schedule domain.DunningSchedule) error {
+	p, err := s.cachedPlan(ctx, sub.NextPlanID(), plans)
+	if err != nil {
+		return err
+	}
+
+	inv := s.periodInvoice(sub, p, domain.InvoiceReasonRenewal, *sub.CurrentPeriodEnd)
+	err = s.collectPeriodPayment(ctx, sub, inv)
+	if err == nil {
+		if err := sub.RecoverFromPastDue(p); err != nil {
+			return err
+		}
+		event, err := domain.NewOutboxMessage(QueueSubscriptionRenewed, SubscriptionRenewedEvent{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID,
+			PlanID:         sub.PlanID,
+			PeriodStart:    *sub.CurrentPeriodStart,
+			PeriodEnd:      *sub.CurrentPeriodEnd,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SaveWithInvoice(ctx, sub, inv, event); err != nil {
+			return s.refundUnsaved(ctx, inv, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, domain.ErrPaymentFailed) {
+		return err
+	}
+
+	exhausted := sub.RecordFailedDunningAttempt(now, schedule)
+	attempt, nextAttemptAt := sub.DunningAttempts, sub.NextDunningAt
+	if exhausted {
+		if err := sub.Cancel(); err != nil {
+			return err
+		}
+	}
+	return s.savePaymentFailure(ctx, sub, attempt, nextAttemptAt, err)
+}
+
+// startDunning é chamado quando a cobrança de renovação é recusada.
+func (s *Service) startDunning(ctx context.Context, sub *domain.Subscription, now time.Time, schedule domain.DunningSchedule, cause error) error {
+	if err := sub.StartDunning(now, schedule); err != nil {
+		return err
+	}
+	// Sem tentativas configuradas a assinatura é encerrada de imediato.
+	if sub.NextDunningAt == nil {
+		if err := sub.Cancel(); err != nil {
+			return err
+		}
+	}
+	return s.savePaymentFailure(ctx, sub, 0, sub.NextDunningAt, cause)
+}
+
+// savePaymentFailure grava a assinatura junto com a notificação da tentativa falhada.
+func (s *Service) savePaymentFailure(ctx context.Context, sub *domain.Subscription, attempt int, nextAttemptAt *time.Time, cause error) error {
+	event, err := s.paymentFailedEvent(ctx, sub, attempt, nextAttemptAt, cause)
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveWithEvents(ctx, sub, event)
+}
+
+func (s *Service) paymentFailedEvent(ctx context.Context, sub *domain.Subscription, attempt int, nextAttemptAt *time.Time, cause error) (*domain.OutboxMessage, error) {
+	user, err := s.userRepo.FindUserByID(ctx, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewOutboxMessage(QueuePaymentFailed, PaymentFailedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Email:          user.Email,
+		Status:         sub.Status,
+		Attempt:        attempt,
+		Reason:         cause.Error(),
+		NextAttemptAt:  nextAttemptAt,
+		Final:          sub.Status == domain.StatusCancelled,
+	})
+}
@@ -0,0 +1,101 @@
+package subscription
+
+import (
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+const (
+	QueueSubscriptionCreated   = "subscription_created_events"
+	QueueSubscriptionRenewed   = "subscription_renewed_events"
+	QueueTrialEnded            = "subscription_trial_ended_events"
+	QueueTrialWillEnd          = "subscription_trial_will_end_events"
+	QueuePlanChanged           = "subscription_plan_changed_events"
+	QueueSubscriptionPaused    = "subscription_paused_events"
+	QueueSubscriptionResumed   = "subscription_resumed_events"
+	QueueSubscriptionCancelled = "subscription_cancelled_events"
+	QueuePaymentFailed         = "subscription_payment_failed_events"
+)
+
+// SubscriptionCreatedEvent leva o plano assinado, para que o worker decida o trial sem o consultar.
+type SubscriptionCreatedEvent struct {
+	SubscriptionID string       `json:"subscriptionId"`
+	UserID         string       `json:"userId"`
+	Email          string       `json:"email"`
+	Plan           *domain.Plan `json:"plan"`
+}
+
+type SubscriptionRenewedEvent struct {
+	SubscriptionID string    `json:"subscriptionId"`
+	UserID         string    `json:"userId"`
+	PlanID         string    `json:"planId"`
+	PeriodStart    time.Time `json:"periodStart"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+}
+
+// TrialEndedEvent corresponde ao evento subscription.trial_ended.
+type TrialEndedEvent struct {
+	SubscriptionID string        `json:"subscriptionId"`
+	UserID         string        `json:"userId"`
+	Email          string        `json:"email"`
+	Status         domain.Status `json:"status"`
+	TrialEndedAt   time.Time     `json:"trialEndedAt"`
+}
+
+// TrialWillEndEvent é o lembrete enviado alguns dias antes do fim do trial.
+type TrialWillEndEvent struct {
+	SubscriptionID string    `json:"subscriptionId"`
+	UserID         string    `json:"userId"`
+	Email          string    `json:"email"`
+	TrialEndsAt    time.Time `json:"trialEndsAt"`
+}
+
+// PlanChangedEvent corresponde ao evento subscription.plan_changed. Em trocas agendadas
+// EffectiveAt é o fim do período corrente e Proration é omitido.
+type PlanChangedEvent struct {
+	SubscriptionID string                `json:"subscriptionId"`
+	UserID         string                `json:"userId"`
+	PreviousPlanID string                `json:"previousPlanId"`
+	NewPlanID      string                `json:"newPlanId"`
+	Mode           domain.PlanChangeMode `json:"mode"`
+	EffectiveAt    time.Time             `json:"effectiveAt"`
+	Proration      *domain.Proration     `json:"proration,omitempty"`
+}
+
+type SubscriptionPausedEvent struct {
+	SubscriptionID string     `json:"subscriptionId"`
+	UserID         string     `json:"userId"`
+	PausedAt       time.Time  `json:"pausedAt"`
+	ResumesAt      *time.Time `json:"resumesAt,omitempty"`
+}
+
+// SubscriptionResumedEvent traz o novo fim do período, já estendido pelo tempo em pausa.
+type SubscriptionResumedEvent struct {
+	SubscriptionID string    `json:"subscriptionId"`
+	UserID         string    `json:"userId"`
+	PausedAt       time.Time `json:"pausedAt"`
+	ResumedAt      time.Time `json:"resumedAt"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+}
+
+// SubscriptionCancelledEvent é publicado quando um cancelamento agendado entra em vigor no fim do período.
+type SubscriptionCancelledEvent struct {
+	SubscriptionID string    `json:"subscriptionId"`
+	UserID         string    `json:"userId"`
+	CancelledAt    time.Time `json:"cancelledAt"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+}
+
+// PaymentFailedEvent notifica cada cobrança recusada durante o dunning. Attempt é zero para a
+// falha da renovação original; Final indica que a cadência se esgotou e a assinatura foi cancelada.
+type PaymentFailedEvent struct {
+	SubscriptionID string        `json:"subscriptionId"`
+	UserID         string        `json:"userId"`
+	Email          string        `json:"email"`
+	Status         domain.Status `json:"status"`
+	Attempt        int           `json:"attempt"`
+	Reason         string        `json:"reason"`
+	NextAttemptAt  *time.Time    `json:"nextAttemptAt,omitempty"`
+	Final          bool          `json:"final"`
+}
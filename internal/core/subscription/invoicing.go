@@ -0,0 +1,93 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// BillingConfig reúne as regras de faturação aplicadas a todas as faturas.
+type BillingConfig struct {
+	// TaxRateBasisPoints é a taxa de imposto em pontos base (ex: 1000 = 10%); zero desliga o imposto.
+	TaxRateBasisPoints int
+}
+
+// periodInvoice fatura um período completo do plano p a começar em start.
+func (s *Service) periodInvoice(sub *domain.Subscription, p *domain.Plan, reason domain.InvoiceReason, start time.Time) *domain.Invoice {
+	end := p.NextPeriodEnd(start)
+	inv := domain.NewInvoice(sub, reason, p.Currency, start, end, time.Now().UTC())
+	inv.AddLine(domain.InvoiceLinePlan, planLineDescription(p, start, end), p.Price)
+	sub.ApplyDiscount(inv)
+	inv.ApplyTax(s.billing.TaxRateBasisPoints)
+	sub.ApplyCredit(inv)
+	return inv
+}
+
+// collectPeriodPayment cobra uma fatura criada por periodInvoice e, se for paga, consome um
+// período do desconto e o crédito usado.
+func (s *Service) collectPeriodPayment(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice) error {
+	if err := s.payments.CollectPayment(ctx, sub, inv); err != nil {
+		return err
+	}
+	sub.ConsumeDiscount()
+	sub.ConsumeCredit(inv)
+	return nil
+}
+
+// planChangeInvoice fatura uma troca imediata de plano a partir do rateio calculado.
+// Com ciclos diferentes o novo plano é faturado por um período completo, com o desconto de uma
+// renovação. Um total negativo
+// (downgrade) é transferido para o saldo de crédito por collectPlanChangePayment.
+func (s *Service) planChangeInvoice(sub *domain.Subscription, current, next *domain.Plan, proration domain.Proration, now time.Time) *domain.Invoice {
+	inv := domain.NewInvoice(sub, domain.InvoiceReasonPlanChange, proration.Currency, now, *sub.CurrentPeriodEnd, now)
+	if proration.Credit > 0 {
+		inv.AddLine(domain.InvoiceLineProration, "Unused time on "+current.Name, -proration.Credit)
+	}
+	if current.SameBillingCycle(next) {
+		if proration.Charge > 0 {
+			inv.AddLine(domain.InvoiceLineProration, "Remaining time on "+next.Name, proration.Charge)
+		}
+		sub.ApplyProrationDiscount(inv)
+	} else {
+		inv.AddLine(domain.InvoiceLinePlan, planLineDescription(next, now, *sub.CurrentPeriodEnd), proration.Charge)
+		sub.ApplyNewPeriodDiscount(inv, proration.Charge, proration.Credit)
+	}
+	inv.ApplyTax(s.billing.TaxRateBasisPoints)
+	sub.ApplyCredit(inv)
+	return inv
+}
+
+// collectPlanChangePayment cobra a fatura de uma troca de plano. O crédito de um downgrade
+// não é perdido: passa para o saldo da assinatura e abate as faturas seguintes. Se a troca
+// iniciou um novo período (newPeriod), este consome um período do desconto.
+func (s *Service) collectPlanChangePayment(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice, newPeriod bool) error {
+	sub.CarryCredit(inv)
+	if err := s.payments.CollectPayment(ctx, sub, inv); err != nil {
+		return err
+	}
+	if newPeriod {
+		sub.ConsumeDiscount()
+	}
+	sub.ConsumeCredit(inv)
+	return nil
+}
+
+// refundUnsaved é chamado quando a gravação de uma fatura já cobrada falha (ex: conflito de
+// versão). A cobrança é reembolsada para que a repetição da operação, que gera uma nova fatura,
+// não cobre o cliente duas vezes.
+func (s *Service) refundUnsaved(ctx context.Context, inv *domain.Invoice, saveErr error) error {
+	if inv.ChargeID == nil {
+		return saveErr
+	}
+	if err := s.payments.RefundInvoice(context.WithoutCancel(ctx), inv); err != nil {
+		return errors.Join(saveErr, fmt.Errorf("refunding charge %s of unsaved invoice %s: %w", *inv.ChargeID, inv.ID, err))
+	}
+	return saveErr
+}
+
+func planLineDescription(p *domain.Plan, start, end time.Time) string {
+	return fmt.Sprintf("%s (%s - %s)", p.Name, start.Format(time.DateOnly), end.Format(time.DateOnly))
+}
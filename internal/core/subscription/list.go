@@ -0,0 +1,113 @@
+package subscription
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Cursor aponta para a última assinatura de uma página; a página seguinte começa logo depois dela.
+type Cursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+// ListFilter é o critério de listagem entregue ao repositório.
+type ListFilter struct {
+	Status     domain.Status
+	PlanID     string
+	Descending bool
+	Limit      int
+	After      *Cursor
+}
+
+type ListInput struct {
+	Status string `json:"status" validate:"omitempty,oneof=PENDING TRIAL ACTIVE PAST_DUE CANCELLED EXPIRED PAUSED"`
+	PlanID string `json:"planId"`
+	Sort   string `json:"sort" validate:"omitempty,oneof=created_at -created_at"`
+	Limit  int    `json:"limit" validate:"gte=0,lte=100"`
+	Cursor string `json:"cursor"`
+}
+
+type ListResult struct {
+	Data       []*domain.Subscription `json:"data"`
+	NextCursor string                 `json:"nextCursor,omitempty"`
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, userID string, input ListInput) (*ListResult, error) {
+	filter, err := input.toFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pede um item a mais para saber se existe uma próxima página.
+	pageSize := filter.Limit
+	filter.Limit++
+
+	subs, err := s.repo.ListByUser(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return newListResult(subs, pageSize), nil
+}
+
+func (in ListInput) toFilter() (ListFilter, error) {
+	filter := ListFilter{
+		Status:     domain.Status(in.Status),
+		PlanID:     in.PlanID,
+		Descending: in.Sort != "created_at",
+		Limit:      in.Limit,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+	if in.Cursor != "" {
+		cursor, err := DecodeCursor(in.Cursor)
+		if err != nil {
+			return ListFilter{}, err
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+func newListResult(subs []*domain.Subscription, pageSize int) *ListResult {
+	result := &ListResult{Data: subs}
+	if len(subs) > pageSize {
+		result.Data = subs[:pageSize]
+		last := result.Data[pageSize-1]
+		result.NextCursor = EncodeCursor(Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return result
+}
+
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
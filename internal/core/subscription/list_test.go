@@ -0,0 +1,52 @@
+package subscription
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{CreatedAt: time.Date(2025, time.May, 4, 12, 30, 0, 0, time.UTC), ID: "sub-1"}
+
+	got, err := DecodeCursor(EncodeCursor(want))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("DecodeCursor() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, raw := range []string{"not-base64!", "bm90LWpzb24", "e30"} {
+		if _, err := DecodeCursor(raw); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", raw, err)
+		}
+	}
+}
+
+func TestNewListResultSetsNextCursorOnlyWhenMorePagesExist(t *testing.T) {
+	now := time.Now().UTC()
+	subs := []*domain.Subscription{
+		{ID: "a", CreatedAt: now},
+		{ID: "b", CreatedAt: now.Add(-time.Minute)},
+		{ID: "c", CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	page := newListResult(subs, 2)
+	if len(page.Data) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 items and a next cursor, got %d items and %q", len(page.Data), page.NextCursor)
+	}
+	cursor, err := DecodeCursor(page.NextCursor)
+	if err != nil || cursor.ID != "b" {
+		t.Errorf("next cursor = %+v (%v), want cursor pointing at b", cursor, err)
+	}
+
+	last := newListResult(subs, 3)
+	if len(last.Data) != 3 || last.NextCursor != "" {
+		t.Errorf("expected last page without cursor, got %d items and %q", len(last.Data), last.NextCursor)
+	}
+}
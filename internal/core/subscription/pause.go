@@ -0,0 +1,106 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// PauseInput permite agendar a retoma automática; sem ResumesAt a pausa dura até ser retomada manualmente.
+type PauseInput struct {
+	ResumesAt *time.Time `json:"resumesAt,omitempty"`
+}
+
+func (s *Service) PauseSubscription(ctx context.Context, userID, subscriptionID string, expectedVersion int, input PauseInput) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+
+	if err := sub.Pause(time.Now().UTC(), input.ResumesAt); err != nil {
+		return nil, err
+	}
+	event, err := domain.NewOutboxMessage(QueueSubscriptionPaused, SubscriptionPausedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		PausedAt:       *sub.PausedAt,
+		ResumesAt:      sub.ResumesAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveWithEvents(ctx, sub, event); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) ResumeSubscription(ctx context.Context, userID, subscriptionID string, expectedVersion int) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+
+	if err := s.resume(ctx, sub, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ResumeDueSubscriptions retoma as assinaturas pausadas cuja data de retoma agendada já passou.
+func (s *Service) ResumeDueSubscriptions(ctx context.Context, now time.Time, batchSize int) (int, error) {
+	subs, err := s.repo.FindPausedDueForResume(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.resume(ctx, sub, now); err != nil {
+			errs = append(errs, fmt.Errorf("resuming subscription %s: %w", sub.ID, err))
+			continue
+		}
+		resumed++
+	}
+	return resumed, errors.Join(errs...)
+}
+
+func (s *Service) resume(ctx context.Context, sub *domain.Subscription, now time.Time) error {
+	pausedAt := sub.PausedAt
+	if err := sub.Resume(now); err != nil {
+		return err
+	}
+
+	resumed := SubscriptionResumedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		ResumedAt:      now,
+	}
+	if pausedAt != nil {
+		resumed.PausedAt = *pausedAt
+	}
+	if sub.CurrentPeriodEnd != nil {
+		resumed.PeriodEnd = *sub.CurrentPeriodEnd
+	}
+	event, err := domain.NewOutboxMessage(QueueSubscriptionResumed, resumed)
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveWithEvents(ctx, sub, event)
+}
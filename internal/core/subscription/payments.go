@@ -0,0 +1,22 @@
+package subscription
+
+import (
+	"context"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// PaymentCollector é o que o serviço de assinaturas precisa do módulo de pagamentos
+// (implementado por payment.Service).
+//
+// CollectPayment cobra o valor devido da fatura e marca-a como paga. Deve devolver um erro
+// que satisfaça errors.Is(err, domain.ErrPaymentFailed) quando a cobrança for recusada; qualquer
+// outro erro é tratado como transitório e a cobrança é repetida no próximo ciclo.
+//
+// RefundInvoice devolve o valor cobrado pela fatura; é usado quando a cobrança foi feita mas
+// a gravação que a acompanha falhou.
+type PaymentCollector interface {
+	CollectPayment(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice) error
+	RefundInvoice(ctx context.Context, inv *domain.Invoice) error
+	AttachPaymentMethod(ctx context.Context, userID, paymentMethodID string) error
+}
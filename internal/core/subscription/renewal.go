@@ -0,0 +1,101 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// RenewDueSubscriptions cobra e avança o período de todas as assinaturas ativas cujo período
+// terminou até now, publicando um evento de renovação para cada uma. As que tinham o
+// cancelamento agendado são canceladas em vez de renovadas e as que têm a cobrança recusada
+// entram em dunning segundo a cadência informada.
+func (s *Service) RenewDueSubscriptions(ctx context.Context, now time.Time, dunning domain.DunningSchedule, batchSize int) (int, error) {
+	subs, err := s.repo.FindDueForRenewal(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	plans := make(map[string]*domain.Plan)
+	renewed := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.renew(ctx, sub, plans, now, dunning); err != nil {
+			errs = append(errs, fmt.Errorf("renewing subscription %s: %w", sub.ID, err))
+			continue
+		}
+		if sub.Status == domain.StatusActive {
+			renewed++
+		}
+	}
+	return renewed, errors.Join(errs...)
+}
+
+func (s *Service) renew(ctx context.Context, sub *domain.Subscription, plans map[string]*domain.Plan, now time.Time, dunning domain.DunningSchedule) error {
+	if sub.CancelAtPeriodEnd {
+		return s.cancelAtPeriodEnd(ctx, sub)
+	}
+
+	p, err := s.cachedPlan(ctx, sub.NextPlanID(), plans)
+	if err != nil {
+		return err
+	}
+
+	inv := s.periodInvoice(sub, p, domain.InvoiceReasonRenewal, *sub.CurrentPeriodEnd)
+	if err := s.collectPeriodPayment(ctx, sub, inv); err != nil {
+		if errors.Is(err, domain.ErrPaymentFailed) {
+			return s.startDunning(ctx, sub, now, dunning, err)
+		}
+		return err
+	}
+	if err := sub.Renew(p); err != nil {
+		return err
+	}
+	event, err := domain.NewOutboxMessage(QueueSubscriptionRenewed, SubscriptionRenewedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		PlanID:         sub.PlanID,
+		PeriodStart:    *sub.CurrentPeriodStart,
+		PeriodEnd:      *sub.CurrentPeriodEnd,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SaveWithInvoice(ctx, sub, inv, event); err != nil {
+		return s.refundUnsaved(ctx, inv, err)
+	}
+	return nil
+}
+
+func (s *Service) cancelAtPeriodEnd(ctx context.Context, sub *domain.Subscription) error {
+	periodEnd := *sub.CurrentPeriodEnd
+	if err := sub.Cancel(); err != nil {
+		return err
+	}
+	event, err := domain.NewOutboxMessage(QueueSubscriptionCancelled, SubscriptionCancelledEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		CancelledAt:    *sub.CancelledAt,
+		PeriodEnd:      periodEnd,
+	})
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveWithEvents(ctx, sub, event)
+}
+
+// cachedPlan evita buscar o mesmo plano repetidamente durante um lote de processamento.
+func (s *Service) cachedPlan(ctx context.Context, planID string, plans map[string]*domain.Plan) (*domain.Plan, error) {
+	if p, ok := plans[planID]; ok {
+		return p, nil
+	}
+	p, err := s.planRepo.FindByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	plans[planID] = p
+	return p, nil
+}
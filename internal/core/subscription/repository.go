@@ -2,11 +2,30 @@ package subscription
 
 import (
 	"context"
+	"time"
 
 	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
 type Repository interface {
 	Save(ctx context.Context, sub *domain.Subscription) error
+	SaveWithEvents(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error
+	// SaveWithInvoice grava a assinatura, a fatura (atribuindo-lhe o número) e os eventos numa só transação.
+	SaveWithInvoice(ctx context.Context, sub *domain.Subscription, inv *domain.Invoice, events ...*domain.OutboxMessage) error
+	// SaveWithRedemption grava uma nova assinatura e o resgate do cupom que ela usou. Devolve
+	// domain.ErrCouponExhausted ou domain.ErrCouponAlreadyRedeemed se outro resgate chegar primeiro.
+	SaveWithRedemption(ctx context.Context, sub *domain.Subscription, redemption *domain.CouponRedemption, events ...*domain.OutboxMessage) error
+	// SaveReleasingRedemption grava a assinatura e desfaz o resgate de cupom que ela tiver
+	// (apaga-o e devolve-o ao contador do cupom) numa só transação.
+	SaveReleasingRedemption(ctx context.Context, sub *domain.Subscription, events ...*domain.OutboxMessage) error
 	FindByID(ctx context.Context, id string) (*domain.Subscription, error)
+	ListByUser(ctx context.Context, userID string, filter ListFilter) ([]*domain.Subscription, error)
+	ListAll(ctx context.Context, filter ListFilter) ([]*domain.Subscription, error)
+	FindDueForRenewal(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error)
+	FindTrialsEndedBefore(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error)
+	FindTrialsEndingBetween(ctx context.Context, from, to time.Time, limit int) ([]*domain.Subscription, error)
+	FindPausedDueForResume(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error)
+	FindDunningDue(ctx context.Context, now time.Time, limit int) ([]*domain.Subscription, error)
+	// HasHadTrial indica se o utilizador já teve um trial num plano da família indicada.
+	HasHadTrial(ctx context.Context, userID, planFamily string) (bool, error)
 }
@@ -2,56 +2,78 @@ package subscription
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/manuzokas/subscription-api/internal/core/auth"
+	"github.com/manuzokas/subscription-api/internal/core/coupon"
+	"github.com/manuzokas/subscription-api/internal/core/plan"
 	"github.com/manuzokas/subscription-api/internal/domain"
 )
 
-type MessagePublisher interface {
-	Publish(ctx context.Context, queueName string, body []byte) error
-}
-
 type Service struct {
-	repo      Repository
-	userRepo  auth.UserRepository
-	publisher MessagePublisher
+	repo     Repository
+	userRepo auth.UserRepository
+	planRepo plan.Repository
+	coupons  coupon.Repository
+	payments PaymentCollector
+	billing  BillingConfig
 }
 
-func NewService(repo Repository, userRepo auth.UserRepository, publisher MessagePublisher) *Service {
+func NewService(repo Repository, userRepo auth.UserRepository, planRepo plan.Repository, coupons coupon.Repository, payments PaymentCollector, billing BillingConfig) *Service {
 	return &Service{
-		repo:      repo,
-		userRepo:  userRepo,
-		publisher: publisher,
+		repo:     repo,
+		userRepo: userRepo,
+		planRepo: planRepo,
+		coupons:  coupons,
+		payments: payments,
+		billing:  billing,
 	}
 }
 
 type CreateSubscriptionInput struct {
-	PlanID string `json:"planId" validate:"required"`
+	PlanID     string `json:"planId" validate:"required"`
+	CouponCode string `json:"couponCode" validate:"omitempty,max=50"`
+	// PaymentMethodID permite cobrar de imediato quando o plano não concede trial.
+	PaymentMethodID string `json:"paymentMethodId"`
 }
 
-type SubscriptionCreatedEvent struct {
-	SubscriptionID string `json:"subscriptionId"`
-	UserID         string `json:"userId"`
-	Email          string `json:"email"`
+type AttachPaymentMethodInput struct {
+	PaymentMethodID string `json:"paymentMethodId" validate:"required"`
 }
 
 func (s *Service) CreateSubscription(ctx context.Context, userID string, input CreateSubscriptionInput) (*domain.Subscription, error) {
+	p, err := s.planRepo.FindByID(ctx, input.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Active {
+		return nil, domain.ErrPlanInactive
+	}
+
 	now := time.Now().UTC()
 
 	newSubscription := &domain.Subscription{
 		ID:        uuid.NewString(),
 		UserID:    userID,
-		PlanID:    input.PlanID,
-		Status:    "PENDING",
+		PlanID:    p.ID,
+		Status:    domain.StatusPending,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if err := s.repo.Save(ctx, newSubscription); err != nil {
-		return nil, err
+	var redemption *domain.CouponRedemption
+	if input.CouponCode != "" {
+		if redemption, err = s.redeemCoupon(ctx, newSubscription, p, input.CouponCode, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.PaymentMethodID != "" {
+		if err := s.payments.AttachPaymentMethod(ctx, userID, input.PaymentMethodID); err != nil {
+			return nil, err
+		}
+		newSubscription.AttachPaymentMethod(input.PaymentMethodID)
 	}
 
 	user, err := s.userRepo.FindUserByID(ctx, userID)
@@ -59,18 +81,22 @@ func (s *Service) CreateSubscription(ctx context.Context, userID string, input C
 		return nil, err
 	}
 
-	event := SubscriptionCreatedEvent{
+	event, err := domain.NewOutboxMessage(QueueSubscriptionCreated, SubscriptionCreatedEvent{
 		SubscriptionID: newSubscription.ID,
 		UserID:         newSubscription.UserID,
 		Email:          user.Email,
-	}
-	eventBody, err := json.Marshal(event)
+		Plan:           p,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	const queueName = "subscription_created_events"
-	if err := s.publisher.Publish(ctx, queueName, eventBody); err != nil {
+	if redemption != nil {
+		err = s.repo.SaveWithRedemption(ctx, newSubscription, redemption, event)
+	} else {
+		err = s.repo.SaveWithEvents(ctx, newSubscription, event)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -88,7 +114,7 @@ func (s *Service) GetSubscriptionByID(ctx context.Context, userID, subscriptionI
 	return sub, nil
 }
 
-func (s *Service) CancelSubscription(ctx context.Context, userID, subscriptionID string) error {
+func (s *Service) CancelSubscription(ctx context.Context, userID, subscriptionID string, expectedVersion int) error {
 	sub, err := s.repo.FindByID(ctx, subscriptionID)
 	if err != nil {
 		return err
@@ -96,9 +122,79 @@ func (s *Service) CancelSubscription(ctx context.Context, userID, subscriptionID
 	if sub.UserID != userID {
 		return domain.ErrForbidden
 	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return err
+	}
 	if !sub.CanBeCancelled() {
 		return domain.ErrSubscriptionCannotBeCancelled
 	}
-	sub.Cancel()
+	if err := sub.Cancel(); err != nil {
+		return err
+	}
 	return s.repo.Save(ctx, sub)
 }
+
+func (s *Service) AttachPaymentMethod(ctx context.Context, userID, subscriptionID string, expectedVersion int, input AttachPaymentMethodInput) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	if err := s.payments.AttachPaymentMethod(ctx, userID, input.PaymentMethodID); err != nil {
+		return nil, err
+	}
+	sub.AttachPaymentMethod(input.PaymentMethodID)
+	// Com um novo meio de pagamento, uma assinatura em atraso é cobrada no próximo ciclo do dunning.
+	sub.RetryDunningNow(sub.UpdatedAt)
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ScheduleCancellation agenda o cancelamento para o fim do período já pago.
+func (s *Service) ScheduleCancellation(ctx context.Context, userID, subscriptionID string, expectedVersion int) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	if err := sub.ScheduleCancellation(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ReactivateSubscription desfaz um cancelamento agendado enquanto a assinatura ainda está ativa.
+func (s *Service) ReactivateSubscription(ctx context.Context, userID, subscriptionID string, expectedVersion int) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+	if err := checkVersion(sub, expectedVersion); err != nil {
+		return nil, err
+	}
+	if err := sub.UndoScheduledCancellation(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
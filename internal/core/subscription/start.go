@@ -0,0 +1,76 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// ErrAlreadyStarted é retornado por StartSubscription quando a assinatura já saiu de PENDING,
+// por exemplo numa entrega repetida do evento de criação.
+var ErrAlreadyStarted = errors.New("subscription has already been started")
+
+// StartSubscription processa uma assinatura recém-criada. O utilizador recebe o trial do plano
+// quando este tem TrialDays e ele ainda não teve um trial na mesma família; caso contrário o
+// primeiro período é cobrado de imediato e, se a cobrança for recusada, a assinatura é cancelada.
+// p é o plano enviado no evento de criação; eventos antigos não o trazem e o plano é consultado.
+func (s *Service) StartSubscription(ctx context.Context, subscriptionID string, p *domain.Plan, now time.Time) (*domain.Subscription, error) {
+	sub, err := s.repo.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != domain.StatusPending {
+		return sub, ErrAlreadyStarted
+	}
+	if p == nil {
+		if p, err = s.planRepo.FindByID(ctx, sub.PlanID); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.TrialDays > 0 {
+		hadTrial, err := s.repo.HasHadTrial(ctx, sub.UserID, p.Family)
+		if err != nil {
+			return nil, err
+		}
+		if !hadTrial {
+			if err := sub.StartTrial(p, now); err != nil {
+				return nil, err
+			}
+			if err := s.repo.Save(ctx, sub); err != nil {
+				return nil, err
+			}
+			return sub, nil
+		}
+	}
+
+	inv := s.periodInvoice(sub, p, domain.InvoiceReasonActivation, now)
+	err = s.collectPeriodPayment(ctx, sub, inv)
+	if errors.Is(err, domain.ErrPaymentFailed) {
+		cause := err
+		if err := sub.Cancel(); err != nil {
+			return nil, err
+		}
+		event, err := s.paymentFailedEvent(ctx, sub, 0, nil, cause)
+		if err != nil {
+			return nil, err
+		}
+		// A assinatura nunca foi paga: o cupom usado volta a estar disponível para o utilizador.
+		if err := s.repo.SaveReleasingRedemption(ctx, sub, event); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.StartFirstPeriod(p, now); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveWithInvoice(ctx, sub, inv); err != nil {
+		return nil, s.refundUnsaved(ctx, inv, err)
+	}
+	return sub, nil
+}
@@ -0,0 +1,82 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+func pendingSubscription(id, planID string) *domain.Subscription {
+	return &domain.Subscription{ID: id, UserID: "user-1", PlanID: planID, Status: domain.StatusPending}
+}
+
+func TestStartSubscriptionGrantsOneTrialPerPlanFamily(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	pm := "pm_visa"
+	second := pendingSubscription("sub-2", "yearly")
+	second.PaymentMethodID = &pm
+	f := newBillingFixture(pendingSubscription("sub-1", "monthly"), second)
+	f.plan.TrialDays = 7
+	f.plan.Family = "pro"
+	yearly := &domain.Plan{ID: "yearly", Name: "Anual", Price: 29900, Currency: "BRL", Interval: domain.BillingIntervalYearly, TrialDays: 30, Family: "pro", Active: true}
+
+	sub, err := f.service.StartSubscription(ctx, "sub-1", f.plan, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != domain.StatusTrial || !sub.TrialEndsAt.Equal(now.AddDate(0, 0, 7)) {
+		t.Fatalf("expected a 7 day trial, got status %s ending %v", sub.Status, sub.TrialEndsAt)
+	}
+
+	sub, err = f.service.StartSubscription(ctx, "sub-2", yearly, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != domain.StatusActive || !sub.CurrentPeriodEnd.Equal(now.AddDate(1, 0, 0)) {
+		t.Fatalf("expected an active yearly period without trial, got status %s ending %v", sub.Status, sub.CurrentPeriodEnd)
+	}
+	if len(f.repo.invoices) != 1 || f.repo.invoices[0].Reason != domain.InvoiceReasonActivation || f.repo.invoices[0].PaidAt == nil {
+		t.Fatalf("expected a paid activation invoice, got %+v", f.repo.invoices)
+	}
+	if charges := f.gateway.Charges(); len(charges) != 1 || charges[0].Amount != f.repo.invoices[0].Total {
+		t.Errorf("unexpected charges %+v", charges)
+	}
+
+	if _, err := f.service.StartSubscription(ctx, "sub-1", f.plan, now); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("redelivery error = %v, want ErrAlreadyStarted", err)
+	}
+}
+
+func TestStartSubscriptionWithoutTrialCancelsWhenPaymentFails(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(pendingSubscription("sub-1", "monthly"))
+	f.repo.redemptions = []*domain.CouponRedemption{
+		{ID: "red-1", CouponID: "coupon-1", UserID: "user-1", SubscriptionID: "sub-1"},
+		{ID: "red-2", CouponID: "coupon-1", UserID: "user-2", SubscriptionID: "sub-2"},
+	}
+
+	sub, err := f.service.StartSubscription(ctx, "sub-1", f.plan, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != domain.StatusCancelled || len(f.repo.invoices) != 0 {
+		t.Fatalf("expected a cancelled subscription without invoice, got %s and %d invoices", sub.Status, len(f.repo.invoices))
+	}
+	if redeemed, _ := f.coupons.HasRedeemed(ctx, "coupon-1", "user-1"); redeemed || len(f.repo.redemptions) != 1 {
+		t.Errorf("expected only the coupon redemption of the cancelled subscription to be released, got %+v", f.repo.redemptions)
+	}
+
+	payloads := f.repo.queued(QueuePaymentFailed)
+	if len(payloads) != 1 {
+		t.Fatalf("expected one payment failed event, got %d", len(payloads))
+	}
+	var event PaymentFailedEvent
+	if err := json.Unmarshal(payloads[0], &event); err != nil || !event.Final {
+		t.Errorf("expected a final payment failed event, got %+v (%v)", event, err)
+	}
+}
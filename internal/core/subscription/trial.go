@@ -0,0 +1,123 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// ExpireTrials processa as assinaturas em TRIAL cujo período de avaliação já terminou:
+// as que têm meio de pagamento e cuja primeira cobrança é aprovada passam a ACTIVE,
+// as restantes expiram.
+func (s *Service) ExpireTrials(ctx context.Context, now time.Time, batchSize int) (int, error) {
+	subs, err := s.repo.FindTrialsEndedBefore(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	plans := make(map[string]*domain.Plan)
+	processed := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.endTrial(ctx, sub, plans); err != nil {
+			errs = append(errs, fmt.Errorf("ending trial of subscription %s: %w", sub.ID, err))
+			continue
+		}
+		processed++
+	}
+	return processed, errors.Join(errs...)
+}
+
+func (s *Service) endTrial(ctx context.Context, sub *domain.Subscription, plans map[string]*domain.Plan) error {
+	trialEndedAt := *sub.TrialEndsAt
+
+	var inv *domain.Invoice
+	if sub.HasPaymentMethod() {
+		p, err := s.cachedPlan(ctx, sub.PlanID, plans)
+		if err != nil {
+			return err
+		}
+		first := s.periodInvoice(sub, p, domain.InvoiceReasonActivation, trialEndedAt)
+		err = s.collectPeriodPayment(ctx, sub, first)
+		if err != nil && !errors.Is(err, domain.ErrPaymentFailed) {
+			return err
+		}
+		if err == nil {
+			if err := sub.ConvertTrial(p); err != nil {
+				return err
+			}
+			inv = first
+		}
+	}
+	if inv == nil {
+		if err := sub.Expire(); err != nil {
+			return err
+		}
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+
+	event, err := domain.NewOutboxMessage(QueueTrialEnded, TrialEndedEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Email:          user.Email,
+		Status:         sub.Status,
+		TrialEndedAt:   trialEndedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if inv != nil {
+		if err := s.repo.SaveWithInvoice(ctx, sub, inv, event); err != nil {
+			return s.refundUnsaved(ctx, inv, err)
+		}
+		return nil
+	}
+	return s.repo.SaveWithEvents(ctx, sub, event)
+}
+
+// SendTrialEndingReminders publica um lembrete para os trials que terminam dentro de `within`.
+// Cada assinatura recebe no máximo um lembrete.
+func (s *Service) SendTrialEndingReminders(ctx context.Context, now time.Time, within time.Duration, batchSize int) (int, error) {
+	subs, err := s.repo.FindTrialsEndingBetween(ctx, now, now.Add(within), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, sub := range subs {
+		if err := s.remindTrialEnding(ctx, sub, now); err != nil {
+			errs = append(errs, fmt.Errorf("reminding subscription %s: %w", sub.ID, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (s *Service) remindTrialEnding(ctx context.Context, sub *domain.Subscription, now time.Time) error {
+	user, err := s.userRepo.FindUserByID(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+
+	event, err := domain.NewOutboxMessage(QueueTrialWillEnd, TrialWillEndEvent{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Email:          user.Email,
+		TrialEndsAt:    *sub.TrialEndsAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	sub.MarkTrialReminderSent(now)
+	return s.repo.SaveWithEvents(ctx, sub, event)
+}
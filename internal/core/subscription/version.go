@@ -0,0 +1,22 @@
+package subscription
+
+import (
+	"errors"
+
+	"github.com/manuzokas/subscription-api/internal/domain"
+)
+
+// ErrPreconditionFailed é devolvido quando a versão esperada pelo cliente (If-Match) já não é a atual.
+var ErrPreconditionFailed = errors.New("subscription version does not match the expected version")
+
+// AnyVersion dispensa a verificação de versão (o cliente enviou If-Match: *).
+const AnyVersion = 0
+
+// checkVersion garante que o cliente está a alterar a mesma versão que leu. A gravação em si
+// continua protegida pelo controlo otimista do repositório.
+func checkVersion(sub *domain.Subscription, expected int) error {
+	if expected != AnyVersion && sub.Version != expected {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
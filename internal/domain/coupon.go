@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrCouponNotFound é retornado quando não existe cupom com o código ou ID informado.
+var ErrCouponNotFound = errors.New("coupon not found")
+
+// ErrCouponCodeTaken é retornado ao criar um cupom com um código já usado.
+var ErrCouponCodeTaken = errors.New("coupon code is already in use")
+
+// ErrCouponInactive é retornado ao resgatar um cupom desativado.
+var ErrCouponInactive = errors.New("coupon is not active")
+
+// ErrCouponExpired é retornado ao resgatar um cupom depois da data de validade.
+var ErrCouponExpired = errors.New("coupon has expired")
+
+// ErrCouponExhausted é retornado quando o cupom já atingiu o número máximo de resgates.
+var ErrCouponExhausted = errors.New("coupon has reached its maximum number of redemptions")
+
+// ErrCouponNotApplicable é retornado quando o cupom não vale para o plano escolhido.
+var ErrCouponNotApplicable = errors.New("coupon does not apply to this plan")
+
+// ErrCouponAlreadyRedeemed é retornado quando o utilizador já usou o cupom.
+var ErrCouponAlreadyRedeemed = errors.New("coupon has already been redeemed by this user")
+
+// CouponDuration define por quantas cobranças o desconto de um cupom é aplicado.
+type CouponDuration string
+
+const (
+	CouponDurationOnce      CouponDuration = "ONCE"
+	CouponDurationRepeating CouponDuration = "REPEATING"
+	CouponDurationForever   CouponDuration = "FOREVER"
+)
+
+// Coupon é um código promocional resgatável na criação de uma assinatura. O desconto é
+// percentual (PercentOff) ou um valor fixo em unidades mínimas de Currency (AmountOff).
+type Coupon struct {
+	ID         string         `json:"id"`
+	Code       string         `json:"code"`
+	PercentOff int            `json:"percentOff,omitempty"`
+	AmountOff  int64          `json:"amountOff,omitempty"`
+	Currency   string         `json:"currency,omitempty"`
+	Duration   CouponDuration `json:"duration"`
+	// DurationInPeriods é o número de cobranças com desconto de um cupom REPEATING.
+	DurationInPeriods int `json:"durationInPeriods,omitempty"`
+	// MaxRedemptions limita o total de resgates; zero significa ilimitado.
+	MaxRedemptions int        `json:"maxRedemptions,omitempty"`
+	TimesRedeemed  int        `json:"timesRedeemed"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	// PlanIDs restringe o cupom a estes planos; vazio vale para todos.
+	PlanIDs   []string  `json:"planIds"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CheckRedeemable verifica se o cupom pode ser usado numa nova assinatura do plano p.
+// O limite de resgates é verificado de novo ao gravar, pois outros resgates podem concorrer.
+func (c *Coupon) CheckRedeemable(p *Plan, now time.Time) error {
+	if !c.Active {
+		return ErrCouponInactive
+	}
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return ErrCouponExpired
+	}
+	if c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	if len(c.PlanIDs) > 0 && !slices.Contains(c.PlanIDs, p.ID) {
+		return ErrCouponNotApplicable
+	}
+	if c.AmountOff > 0 && c.Currency != p.Currency {
+		return ErrCouponNotApplicable
+	}
+	return nil
+}
+
+// Deactivate impede novos resgates; as assinaturas que já usaram o cupom mantêm o desconto.
+func (c *Coupon) Deactivate(now time.Time) {
+	c.Active = false
+	c.UpdatedAt = now
+}
+
+// NewDiscount copia os termos do cupom para o desconto de uma assinatura.
+func (c *Coupon) NewDiscount() *Discount {
+	d := &Discount{
+		CouponID:   c.ID,
+		Code:       c.Code,
+		PercentOff: c.PercentOff,
+		AmountOff:  c.AmountOff,
+		Currency:   c.Currency,
+		Duration:   c.Duration,
+	}
+	switch c.Duration {
+	case CouponDurationOnce:
+		d.RemainingPeriods = 1
+	case CouponDurationRepeating:
+		d.RemainingPeriods = c.DurationInPeriods
+	}
+	return d
+}
+
+// CouponRedemption regista o uso de um cupom por um utilizador.
+type CouponRedemption struct {
+	ID             string    `json:"id"`
+	CouponID       string    `json:"couponId"`
+	UserID         string    `json:"userId"`
+	SubscriptionID string    `json:"subscriptionId"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// Discount é o desconto de um cupom aplicado às cobranças de uma assinatura. Guarda uma cópia
+// dos termos para que alterar ou desativar o cupom não afete quem já o resgatou.
+type Discount struct {
+	CouponID   string         `json:"couponId"`
+	Code       string         `json:"code"`
+	PercentOff int            `json:"percentOff,omitempty"`
+	AmountOff  int64          `json:"amountOff,omitempty"`
+	Currency   string         `json:"currency,omitempty"`
+	Duration   CouponDuration `json:"duration"`
+	// RemainingPeriods conta as cobranças que ainda recebem desconto; não se aplica a FOREVER.
+	RemainingPeriods int `json:"remainingPeriods,omitempty"`
+}
+
+// Amount calcula o desconto sobre subtotal, nunca maior que o próprio subtotal.
+func (d *Discount) Amount(subtotal int64) int64 {
+	if subtotal <= 0 {
+		return 0
+	}
+	if d.PercentOff > 0 {
+		return subtotal * int64(d.PercentOff) / 100
+	}
+	return min(d.AmountOff, subtotal)
+}
+
+// Description é o texto da linha de desconto na fatura (ex: "Coupon WELCOME (20% off)").
+func (d *Discount) Description() string {
+	if d.PercentOff > 0 {
+		return fmt.Sprintf("Coupon %s (%d%% off)", d.Code, d.PercentOff)
+	}
+	return "Coupon " + d.Code
+}
+
+// ApplyDiscount acrescenta à fatura a linha do desconto ativo da assinatura, se houver.
+// Deve ser chamado antes de ApplyTax, para que o imposto incida sobre o valor com desconto.
+func (s *Subscription) ApplyDiscount(inv *Invoice) {
+	if s.Discount == nil {
+		return
+	}
+	if amount := s.Discount.Amount(inv.Subtotal); amount > 0 {
+		inv.AddLine(InvoiceLineDiscount, s.Discount.Description(), -amount)
+	}
+}
+
+// ApplyProrationDiscount aplica o desconto ao valor líquido do rateio de uma troca de plano no
+// mesmo ciclo, sem consumir um período. Só descontos percentuais são aplicados: o tempo restante foi pago
+// com essa percentagem, por isso tanto a cobrança como o crédito a refletem. Descontos de valor
+// fixo valem por período de faturação e já foram concedidos na fatura do período corrente.
+// Deve ser chamado antes de ApplyTax.
+func (s *Subscription) ApplyProrationDiscount(inv *Invoice) {
+	if s.Discount == nil || s.Discount.PercentOff == 0 || inv.Subtotal == 0 {
+		return
+	}
+	if amount := inv.Subtotal * int64(s.Discount.PercentOff) / 100; amount != 0 {
+		inv.AddLine(InvoiceLineDiscount, s.Discount.Description(), -amount)
+	}
+}
+
+// ApplyNewPeriodDiscount aplica o desconto a uma troca de plano que muda de ciclo. O novo plano
+// é faturado por um período completo, que recebe o desconto como numa renovação e conta como um
+// período do cupom (ver ConsumeDiscount). O crédito do tempo não usado só é reduzido pela parte
+// percentual, com que foi pago. Deve ser chamado antes de ApplyTax.
+func (s *Subscription) ApplyNewPeriodDiscount(inv *Invoice, periodCharge, unusedCredit int64) {
+	if s.Discount == nil {
+		return
+	}
+	amount := s.Discount.Amount(periodCharge)
+	if s.Discount.PercentOff > 0 {
+		amount -= unusedCredit * int64(s.Discount.PercentOff) / 100
+	}
+	if amount != 0 {
+		inv.AddLine(InvoiceLineDiscount, s.Discount.Description(), -amount)
+	}
+}
+
+// ConsumeDiscount desconta uma cobrança paga da duração do cupom, removendo o desconto
+// quando se esgota. Descontos FOREVER nunca se esgotam.
+func (s *Subscription) ConsumeDiscount() {
+	if s.Discount == nil || s.Discount.Duration == CouponDurationForever {
+		return
+	}
+	s.Discount.RemainingPeriods--
+	if s.Discount.RemainingPeriods <= 0 {
+		s.Discount = nil
+	}
+}
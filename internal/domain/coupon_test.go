@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCouponCheckRedeemable(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	plan := &Plan{ID: "basic", Currency: "BRL"}
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		want   error
+	}{
+		{"valid", Coupon{Active: true, PercentOff: 10}, nil},
+		{"inactive", Coupon{PercentOff: 10}, ErrCouponInactive},
+		{"expired", Coupon{Active: true, PercentOff: 10, ExpiresAt: &yesterday}, ErrCouponExpired},
+		{"exhausted", Coupon{Active: true, PercentOff: 10, MaxRedemptions: 2, TimesRedeemed: 2}, ErrCouponExhausted},
+		{"other plan", Coupon{Active: true, PercentOff: 10, PlanIDs: []string{"pro"}}, ErrCouponNotApplicable},
+		{"listed plan", Coupon{Active: true, PercentOff: 10, PlanIDs: []string{"pro", "basic"}}, nil},
+		{"other currency", Coupon{Active: true, AmountOff: 500, Currency: "USD"}, ErrCouponNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.coupon.CheckRedeemable(plan, now); !errors.Is(err, tt.want) {
+				t.Errorf("CheckRedeemable() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDiscount(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		discount *Discount
+		subtotal int64
+		want     int64
+	}{
+		{"percent off", &Discount{Code: "HALF", PercentOff: 50}, 2990, 1495},
+		{"amount off", &Discount{Code: "TEN", AmountOff: 1000, Currency: "BRL"}, 2990, 1990},
+		{"amount off capped at subtotal", &Discount{Code: "BIG", AmountOff: 5000, Currency: "BRL"}, 2990, 0},
+		{"no discount", nil, 2990, 2990},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Subscription{ID: "sub-1", Discount: tt.discount}
+			inv := NewInvoice(sub, InvoiceReasonRenewal, "BRL", now, now, now)
+			inv.AddLine(InvoiceLinePlan, "Basic", tt.subtotal)
+			sub.ApplyDiscount(inv)
+			if inv.Total != tt.want {
+				t.Errorf("total = %d, want %d", inv.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumeDiscount(t *testing.T) {
+	repeating := &Coupon{ID: "c1", Code: "THREE", PercentOff: 10, Duration: CouponDurationRepeating, DurationInPeriods: 3}
+	sub := &Subscription{Discount: repeating.NewDiscount()}
+	for i := 0; i < 2; i++ {
+		sub.ConsumeDiscount()
+	}
+	if sub.Discount == nil || sub.Discount.RemainingPeriods != 1 {
+		t.Fatalf("expected one period left, got %+v", sub.Discount)
+	}
+	sub.ConsumeDiscount()
+	if sub.Discount != nil {
+		t.Errorf("expected the discount to be removed, got %+v", sub.Discount)
+	}
+
+	forever := &Coupon{ID: "c2", Code: "ALWAYS", PercentOff: 10, Duration: CouponDurationForever}
+	sub = &Subscription{Discount: forever.NewDiscount()}
+	for i := 0; i < 12; i++ {
+		sub.ConsumeDiscount()
+	}
+	if sub.Discount == nil {
+		t.Error("forever discount should never run out")
+	}
+}
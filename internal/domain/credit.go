@@ -0,0 +1,29 @@
+package domain
+
+// O saldo de crédito guarda o valor a favor do cliente (ex.: o rateio de um downgrade),
+// na moeda da assinatura, até ser usado para abater as faturas seguintes.
+
+// CarryCredit transfere para o saldo da assinatura o crédito de uma fatura com total negativo.
+// A fatura fica a zero, com uma linha que indica para onde foi o crédito.
+func (s *Subscription) CarryCredit(inv *Invoice) {
+	if inv.Total >= 0 {
+		return
+	}
+	credit := -inv.Total
+	inv.AddLine(InvoiceLineCredit, "Credit added to account balance", credit)
+	s.CreditBalance += credit
+}
+
+// ApplyCredit abate o saldo de crédito no total da fatura, sem o consumir: o saldo só é
+// descontado por ConsumeCredit depois de a fatura ser paga. Deve ser chamado depois de ApplyTax.
+func (s *Subscription) ApplyCredit(inv *Invoice) {
+	if s.CreditBalance <= 0 || inv.Total <= 0 {
+		return
+	}
+	inv.AddLine(InvoiceLineCredit, "Account credit applied", -min(s.CreditBalance, inv.Total))
+}
+
+// ConsumeCredit desconta do saldo o crédito aplicado numa fatura já paga.
+func (s *Subscription) ConsumeCredit(inv *Invoice) {
+	s.CreditBalance -= inv.CreditApplied()
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPaymentFailed indica que a cobrança foi recusada e não adianta repeti-la de imediato.
+// Falhas transitórias (ex: timeouts) devem usar outros erros para serem tentadas de novo no próximo ciclo.
+var ErrPaymentFailed = errors.New("payment failed")
+
+// DunningSchedule define, a partir do momento em que a assinatura entrou em PAST_DUE,
+// quando cada nova tentativa de cobrança deve ser feita (ex: dias 1, 3 e 7).
+type DunningSchedule []time.Duration
+
+// DunningScheduleFromDays constrói a cadência a partir de uma lista de dias.
+func DunningScheduleFromDays(days []int) DunningSchedule {
+	schedule := make(DunningSchedule, 0, len(days))
+	for _, d := range days {
+		schedule = append(schedule, time.Duration(d)*24*time.Hour)
+	}
+	return schedule
+}
+
+// NextAttempt devolve o momento da tentativa seguinte depois de `attempts` tentativas falhadas,
+// ou false quando a cadência se esgotou.
+func (d DunningSchedule) NextAttempt(since time.Time, attempts int) (time.Time, bool) {
+	if attempts < 0 || attempts >= len(d) {
+		return time.Time{}, false
+	}
+	return since.Add(d[attempts]), true
+}
+
+// StartDunning move uma assinatura ativa cuja cobrança falhou para PAST_DUE e agenda a primeira nova tentativa.
+func (s *Subscription) StartDunning(now time.Time, schedule DunningSchedule) error {
+	if err := s.transitionTo(StatusPastDue, now); err != nil {
+		return err
+	}
+	s.PastDueSince = &now
+	s.DunningAttempts = 0
+	s.NextDunningAt = nil
+	if next, ok := schedule.NextAttempt(now, 0); ok {
+		s.NextDunningAt = &next
+	}
+	return nil
+}
+
+// RecordFailedDunningAttempt regista uma nova tentativa falhada e agenda a seguinte.
+// Devolve true quando não restam tentativas e a assinatura deve ser encerrada.
+func (s *Subscription) RecordFailedDunningAttempt(now time.Time, schedule DunningSchedule) bool {
+	s.DunningAttempts++
+	s.UpdatedAt = now
+	since := now
+	if s.PastDueSince != nil {
+		since = *s.PastDueSince
+	}
+	next, ok := schedule.NextAttempt(since, s.DunningAttempts)
+	if !ok {
+		s.NextDunningAt = nil
+		return true
+	}
+	s.NextDunningAt = &next
+	return false
+}
+
+// RetryDunningNow antecipa a próxima tentativa, por exemplo após o cliente trocar o meio de pagamento.
+func (s *Subscription) RetryDunningNow(now time.Time) {
+	if s.Status == StatusPastDue {
+		s.NextDunningAt = &now
+	}
+}
+
+// RecoverFromPastDue reativa a assinatura depois de uma cobrança bem-sucedida e inicia o
+// período que estava em atraso, mantendo a data de cobrança original.
+func (s *Subscription) RecoverFromPastDue(p *Plan) error {
+	if err := s.Activate(); err != nil {
+		return err
+	}
+	s.clearDunning()
+	return s.Renew(p)
+}
+
+func (s *Subscription) clearDunning() {
+	s.PastDueSince = nil
+	s.DunningAttempts = 0
+	s.NextDunningAt = nil
+}
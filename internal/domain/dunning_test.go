@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDunningLifecycle(t *testing.T) {
+	start := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	plan := &Plan{ID: "monthly", Interval: BillingIntervalMonthly}
+	schedule := DunningScheduleFromDays([]int{1, 3, 7})
+
+	sub := &Subscription{Status: StatusActive, PlanID: plan.ID}
+	sub.StartBillingPeriod(start, end)
+
+	if err := sub.StartDunning(end, schedule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != StatusPastDue || !sub.NextDunningAt.Equal(end.AddDate(0, 0, 1)) {
+		t.Fatalf("got status %s next attempt %v", sub.Status, sub.NextDunningAt)
+	}
+
+	wantNext := []time.Time{end.AddDate(0, 0, 3), end.AddDate(0, 0, 7)}
+	for i, want := range wantNext {
+		if exhausted := sub.RecordFailedDunningAttempt(*sub.NextDunningAt, schedule); exhausted {
+			t.Fatalf("attempt %d: schedule exhausted too early", i+1)
+		}
+		if !sub.NextDunningAt.Equal(want) {
+			t.Fatalf("attempt %d: next attempt = %s, want %s", i+1, sub.NextDunningAt, want)
+		}
+	}
+	if exhausted := sub.RecordFailedDunningAttempt(*sub.NextDunningAt, schedule); !exhausted {
+		t.Fatal("expected schedule to be exhausted after the last attempt")
+	}
+	if sub.DunningAttempts != 3 || sub.NextDunningAt != nil {
+		t.Errorf("got %d attempts, next attempt %v", sub.DunningAttempts, sub.NextDunningAt)
+	}
+}
+
+func TestRecoverFromPastDueKeepsBillingAnchor(t *testing.T) {
+	start := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	plan := &Plan{ID: "monthly", Interval: BillingIntervalMonthly}
+
+	sub := &Subscription{Status: StatusActive, PlanID: plan.ID}
+	sub.StartBillingPeriod(start, end)
+	if err := sub.StartDunning(end, DunningScheduleFromDays([]int{1})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := sub.RecoverFromPastDue(plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != StatusActive || sub.PastDueSince != nil || sub.NextDunningAt != nil {
+		t.Errorf("expected dunning state to be cleared, got %+v", sub)
+	}
+	if !sub.CurrentPeriodStart.Equal(end) || !sub.CurrentPeriodEnd.Equal(end.AddDate(0, 1, 0)) {
+		t.Errorf("unexpected period %s - %s", sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+	}
+}
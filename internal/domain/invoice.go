@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvoiceNotFound é retornado quando a fatura procurada não existe.
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// InvoiceReason indica o evento de cobrança que originou a fatura.
+type InvoiceReason string
+
+const (
+	InvoiceReasonActivation InvoiceReason = "ACTIVATION"
+	InvoiceReasonRenewal    InvoiceReason = "RENEWAL"
+	InvoiceReasonPlanChange InvoiceReason = "PLAN_CHANGE"
+)
+
+// InvoiceLineType classifica as linhas de uma fatura.
+type InvoiceLineType string
+
+const (
+	InvoiceLinePlan      InvoiceLineType = "PLAN"
+	InvoiceLineProration InvoiceLineType = "PRORATION"
+	InvoiceLineDiscount  InvoiceLineType = "DISCOUNT"
+	InvoiceLineTax       InvoiceLineType = "TAX"
+	// InvoiceLineCredit move valor de e para o saldo de crédito da assinatura; não entra no
+	// subtotal (nem no imposto), apenas no total.
+	InvoiceLineCredit InvoiceLineType = "CREDIT"
+)
+
+// InvoiceLine é um item da fatura. Créditos e descontos têm Amount negativo.
+type InvoiceLine struct {
+	Type        InvoiceLineType `json:"type"`
+	Description string          `json:"description"`
+	Amount      int64           `json:"amount"`
+}
+
+// Invoice regista o que foi cobrado a um utilizador. Os valores estão em unidades mínimas
+// da moeda; Number é atribuído sequencialmente pelo repositório ao gravar a fatura.
+type Invoice struct {
+	ID             string        `json:"id"`
+	Number         string        `json:"number"`
+	UserID         string        `json:"userId"`
+	SubscriptionID string        `json:"subscriptionId"`
+	Reason         InvoiceReason `json:"reason"`
+	Currency       string        `json:"currency"`
+	Lines          []InvoiceLine `json:"lines"`
+	Subtotal       int64         `json:"subtotal"`
+	Tax            int64         `json:"tax"`
+	Total          int64         `json:"total"`
+	PeriodStart    time.Time     `json:"periodStart"`
+	PeriodEnd      time.Time     `json:"periodEnd"`
+	ChargeID       *string       `json:"chargeId,omitempty"`
+	PaidAt         *time.Time    `json:"paidAt,omitempty"`
+	CreatedAt      time.Time     `json:"createdAt"`
+}
+
+// NewInvoice cria uma fatura vazia para a assinatura, referente ao período [periodStart, periodEnd).
+func NewInvoice(sub *Subscription, reason InvoiceReason, currency string, periodStart, periodEnd, now time.Time) *Invoice {
+	return &Invoice{
+		ID:             uuid.NewString(),
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		Reason:         reason,
+		Currency:       currency,
+		Lines:          []InvoiceLine{},
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		CreatedAt:      now,
+	}
+}
+
+// FormatInvoiceNumber formata o número sequencial de uma fatura (ex: INV-000042).
+func FormatInvoiceNumber(seq int64) string {
+	return fmt.Sprintf("INV-%06d", seq)
+}
+
+// AddLine acrescenta um item e recalcula os totais.
+func (i *Invoice) AddLine(lineType InvoiceLineType, description string, amount int64) {
+	i.Lines = append(i.Lines, InvoiceLine{Type: lineType, Description: description, Amount: amount})
+	i.recalculate()
+}
+
+// ApplyTax acrescenta o imposto, em pontos base (ex: 1000 = 10%), sobre o subtotal positivo.
+// Deve ser chamado depois de todos os itens e descontos.
+func (i *Invoice) ApplyTax(rateBasisPoints int) {
+	if rateBasisPoints <= 0 || i.Subtotal <= 0 {
+		return
+	}
+	tax := (i.Subtotal*int64(rateBasisPoints) + 5_000) / 10_000
+	i.AddLine(InvoiceLineTax, fmt.Sprintf("Tax (%d.%02d%%)", rateBasisPoints/100, rateBasisPoints%100), tax)
+}
+
+// AmountDue é o valor a cobrar; faturas com total negativo (créditos) não geram cobrança.
+func (i *Invoice) AmountDue() int64 {
+	return max(i.Total, 0)
+}
+
+// MarkPaid regista a cobrança que liquidou a fatura; chargeID vazio indica que nada foi cobrado.
+func (i *Invoice) MarkPaid(chargeID string, now time.Time) {
+	if chargeID != "" {
+		i.ChargeID = &chargeID
+	}
+	i.PaidAt = &now
+}
+
+// CreditApplied é o valor do saldo de crédito usado para abater a fatura.
+func (i *Invoice) CreditApplied() int64 {
+	var applied int64
+	for _, line := range i.Lines {
+		if line.Type == InvoiceLineCredit && line.Amount < 0 {
+			applied -= line.Amount
+		}
+	}
+	return applied
+}
+
+func (i *Invoice) recalculate() {
+	var credit int64
+	i.Subtotal, i.Tax = 0, 0
+	for _, line := range i.Lines {
+		switch line.Type {
+		case InvoiceLineTax:
+			i.Tax += line.Amount
+		case InvoiceLineCredit:
+			credit += line.Amount
+		default:
+			i.Subtotal += line.Amount
+		}
+	}
+	i.Total = i.Subtotal + i.Tax + credit
+}
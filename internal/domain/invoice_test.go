@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvoiceTotals(t *testing.T) {
+	now := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	sub := &Subscription{ID: "sub-1", UserID: "user-1"}
+
+	inv := NewInvoice(sub, InvoiceReasonPlanChange, "BRL", now, now.AddDate(0, 1, 0), now)
+	inv.AddLine(InvoiceLineProration, "Unused time on Basic", -500)
+	inv.AddLine(InvoiceLineProration, "Remaining time on Pro", 1500)
+	inv.AddLine(InvoiceLineDiscount, "Coupon", -100)
+	inv.ApplyTax(1250)
+
+	if inv.Subtotal != 900 || inv.Tax != 113 || inv.Total != 1013 || inv.AmountDue() != 1013 {
+		t.Errorf("got subtotal %d tax %d total %d due %d", inv.Subtotal, inv.Tax, inv.Total, inv.AmountDue())
+	}
+	if last := inv.Lines[len(inv.Lines)-1]; last.Type != InvoiceLineTax || last.Description != "Tax (12.50%)" {
+		t.Errorf("unexpected tax line %+v", last)
+	}
+}
+
+func TestInvoiceCreditIsNotTaxedOrCharged(t *testing.T) {
+	now := time.Now()
+	inv := NewInvoice(&Subscription{ID: "sub-1"}, InvoiceReasonPlanChange, "BRL", now, now, now)
+	inv.AddLine(InvoiceLineProration, "Unused time on Pro", -1500)
+	inv.AddLine(InvoiceLineProration, "Remaining time on Basic", 500)
+	inv.ApplyTax(1000)
+
+	if inv.Tax != 0 || inv.Total != -1000 || inv.AmountDue() != 0 {
+		t.Errorf("got tax %d total %d due %d", inv.Tax, inv.Total, inv.AmountDue())
+	}
+}
+
+func TestCreditBalanceCarriesToTheNextInvoice(t *testing.T) {
+	now := time.Now()
+	sub := &Subscription{ID: "sub-1"}
+
+	downgrade := NewInvoice(sub, InvoiceReasonPlanChange, "BRL", now, now, now)
+	downgrade.AddLine(InvoiceLineProration, "Unused time on Pro", -1500)
+	downgrade.AddLine(InvoiceLineProration, "Remaining time on Basic", 500)
+	sub.CarryCredit(downgrade)
+	if downgrade.Total != 0 || sub.CreditBalance != 1000 {
+		t.Fatalf("got invoice total %d and balance %d, want 0 and 1000", downgrade.Total, sub.CreditBalance)
+	}
+
+	renewal := NewInvoice(sub, InvoiceReasonRenewal, "BRL", now, now, now)
+	renewal.AddLine(InvoiceLinePlan, "Basic", 600)
+	renewal.ApplyTax(1000)
+	sub.ApplyCredit(renewal)
+	if renewal.Subtotal != 600 || renewal.Tax != 60 || renewal.Total != 0 || renewal.CreditApplied() != 660 {
+		t.Fatalf("got subtotal %d tax %d total %d credit %d", renewal.Subtotal, renewal.Tax, renewal.Total, renewal.CreditApplied())
+	}
+	if sub.CreditBalance != 1000 {
+		t.Errorf("credit must only be consumed once the invoice is paid, balance = %d", sub.CreditBalance)
+	}
+	sub.ConsumeCredit(renewal)
+	if sub.CreditBalance != 340 {
+		t.Errorf("balance = %d, want 340", sub.CreditBalance)
+	}
+}
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	if got := FormatInvoiceNumber(42); got != "INV-000042" {
+		t.Errorf("FormatInvoiceNumber(42) = %q", got)
+	}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage é um evento gravado na mesma transação que a alteração de estado que o originou.
+// Um relay publica as mensagens pendentes no broker e marca-as como enviadas.
+type OutboxMessage struct {
+	ID        string
+	Queue     string
+	Payload   []byte
+	CreatedAt time.Time
+	SentAt    *time.Time
+	Attempts  int
+	LastError *string
+}
+
+// NewOutboxMessage serializa o evento e prepara-o para ser publicado na fila indicada.
+func NewOutboxMessage(queue string, event any) (*OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		ID:        uuid.NewString(),
+		Queue:     queue,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPlanNotFound é retornado quando o plano solicitado não existe no catálogo.
+var ErrPlanNotFound = errors.New("plan not found")
+
+// ErrPlanInactive é retornado quando se tenta assinar um plano arquivado.
+var ErrPlanInactive = errors.New("plan is not active")
+
+// BillingInterval define a periodicidade de cobrança de um plano.
+type BillingInterval string
+
+const (
+	BillingIntervalMonthly BillingInterval = "MONTHLY"
+	BillingIntervalYearly  BillingInterval = "YEARLY"
+	BillingIntervalCustom  BillingInterval = "CUSTOM"
+)
+
+// Plan representa um plano do catálogo que pode ser assinado.
+// O preço é guardado em unidades mínimas da moeda (ex: centavos).
+type Plan struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Price        int64           `json:"price"`
+	Currency     string          `json:"currency"`
+	Interval     BillingInterval `json:"interval"`
+	IntervalDays int             `json:"intervalDays,omitempty"`
+	TrialDays    int             `json:"trialDays"`
+	Active       bool            `json:"active"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+
+	// Family agrupa variantes do mesmo produto (ex: mensal e anual); cada utilizador tem no
+	// máximo um trial por família. Por omissão é o ID do próprio plano.
+	Family string `json:"family"`
+}
+
+// NextPeriodEnd calcula o fim de um período de cobrança que começa em start.
+// Planos CUSTOM usam IntervalDays como duração do período.
+func (p *Plan) NextPeriodEnd(start time.Time) time.Time {
+	switch p.Interval {
+	case BillingIntervalYearly:
+		return start.AddDate(1, 0, 0)
+	case BillingIntervalCustom:
+		return start.AddDate(0, 0, p.IntervalDays)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// Archive retira o plano do catálogo sem apagar o histórico das assinaturas existentes.
+func (p *Plan) Archive() {
+	p.Active = false
+	p.UpdatedAt = time.Now().UTC()
+}
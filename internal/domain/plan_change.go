@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPlanUnchanged é retornado quando se pede a troca para o plano que a assinatura já usa.
+var ErrPlanUnchanged = errors.New("subscription is already on this plan")
+
+// ErrPlanCurrencyMismatch é retornado quando os dois planos de uma troca usam moedas diferentes.
+var ErrPlanCurrencyMismatch = errors.New("plans must use the same currency")
+
+// ErrPlanChangeNotAllowed é retornado quando o estado da assinatura não permite trocar de plano.
+var ErrPlanChangeNotAllowed = errors.New("subscription plan cannot be changed in its current status")
+
+// ErrTrialPlanFamilyChange é retornado quando uma assinatura em trial tenta mudar para um plano
+// de outra família: o trial só vale para a família em que foi concedido.
+var ErrTrialPlanFamilyChange = errors.New("a trial cannot be moved to a plan in another family")
+
+// PlanChangeMode define quando uma troca de plano passa a valer.
+type PlanChangeMode string
+
+const (
+	PlanChangeImmediately PlanChangeMode = "immediately"
+	PlanChangeAtPeriodEnd PlanChangeMode = "at_period_end"
+)
+
+// Proration resume os valores de uma troca imediata de plano, em unidades mínimas da moeda.
+// Credit é a parte não usada do plano atual e Charge o custo do novo plano até ao fim do
+// período; AmountDue negativo representa saldo a favor do cliente.
+type Proration struct {
+	Credit    int64  `json:"credit"`
+	Charge    int64  `json:"charge"`
+	AmountDue int64  `json:"amountDue"`
+	Currency  string `json:"currency"`
+}
+
+// SameBillingCycle indica se dois planos têm períodos de igual duração, caso em que uma troca
+// imediata mantém o período corrente em vez de iniciar um novo.
+func (p *Plan) SameBillingCycle(other *Plan) bool {
+	if p.Interval != other.Interval {
+		return false
+	}
+	return p.Interval != BillingIntervalCustom || p.IntervalDays == other.IntervalDays
+}
+
+// CalculateProration calcula o crédito do plano atual e a cobrança do novo plano para uma troca
+// feita em now. Com o mesmo ciclo de cobrança o novo plano é cobrado apenas pelo tempo restante;
+// com ciclos diferentes começa um período novo e o novo plano é cobrado por inteiro.
+func CalculateProration(current, next *Plan, periodStart, periodEnd, now time.Time) (Proration, error) {
+	if current.Currency != next.Currency {
+		return Proration{}, ErrPlanCurrencyMismatch
+	}
+
+	total := int64(periodEnd.Sub(periodStart) / time.Second)
+	remaining := int64(periodEnd.Sub(now) / time.Second)
+	remaining = max(0, min(remaining, total))
+
+	var credit int64
+	if total > 0 {
+		credit = current.Price * remaining / total
+	}
+
+	charge := next.Price
+	if current.SameBillingCycle(next) {
+		charge = 0
+		if total > 0 {
+			charge = next.Price * remaining / total
+		}
+	}
+
+	return Proration{
+		Credit:    credit,
+		Charge:    charge,
+		AmountDue: charge - credit,
+		Currency:  next.Currency,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateProration(t *testing.T) {
+	start := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	halfway := start.AddDate(0, 0, 15)
+
+	basic := &Plan{ID: "basic", Price: 1000, Currency: "BRL", Interval: BillingIntervalCustom, IntervalDays: 30}
+	pro := &Plan{ID: "pro", Price: 3000, Currency: "BRL", Interval: BillingIntervalCustom, IntervalDays: 30}
+	yearly := &Plan{ID: "yearly", Price: 20000, Currency: "BRL", Interval: BillingIntervalYearly}
+
+	tests := []struct {
+		name        string
+		current     *Plan
+		next        *Plan
+		now         time.Time
+		wantCredit  int64
+		wantCharge  int64
+		wantBalance int64
+	}{
+		{"upgrade halfway", basic, pro, halfway, 500, 1500, 1000},
+		{"downgrade halfway", pro, basic, halfway, 1500, 500, -1000},
+		{"upgrade at period start", basic, pro, start, 1000, 3000, 2000},
+		{"after period end", basic, pro, end.Add(time.Hour), 0, 0, 0},
+		{"different cycle charges full price", basic, yearly, halfway, 500, 20000, 19500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalculateProration(tt.current, tt.next, start, end, tt.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Credit != tt.wantCredit || got.Charge != tt.wantCharge || got.AmountDue != tt.wantBalance {
+				t.Errorf("CalculateProration() = %+v, want credit %d charge %d due %d",
+					got, tt.wantCredit, tt.wantCharge, tt.wantBalance)
+			}
+		})
+	}
+
+	usd := &Plan{ID: "usd", Price: 1000, Currency: "USD", Interval: BillingIntervalCustom, IntervalDays: 30}
+	if _, err := CalculateProration(basic, usd, start, end, halfway); err != ErrPlanCurrencyMismatch {
+		t.Errorf("expected ErrPlanCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestSubscriptionChangePlan(t *testing.T) {
+	start := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	now := start.AddDate(0, 0, 10)
+
+	monthly := &Plan{ID: "monthly", Interval: BillingIntervalMonthly}
+	monthlyPro := &Plan{ID: "monthly-pro", Interval: BillingIntervalMonthly}
+	yearly := &Plan{ID: "yearly", Interval: BillingIntervalYearly}
+
+	t.Run("same cycle keeps the current period", func(t *testing.T) {
+		sub := &Subscription{Status: StatusActive, PlanID: monthly.ID}
+		sub.StartBillingPeriod(start, end)
+		if err := sub.ChangePlan(monthly, monthlyPro, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sub.PlanID != monthlyPro.ID || !sub.CurrentPeriodEnd.Equal(end) {
+			t.Errorf("got plan %s period end %s", sub.PlanID, sub.CurrentPeriodEnd)
+		}
+	})
+
+	t.Run("different cycle starts a new period", func(t *testing.T) {
+		sub := &Subscription{Status: StatusActive, PlanID: monthly.ID}
+		sub.StartBillingPeriod(start, end)
+		if err := sub.ChangePlan(monthly, yearly, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !sub.CurrentPeriodStart.Equal(now) || !sub.CurrentPeriodEnd.Equal(now.AddDate(1, 0, 0)) {
+			t.Errorf("unexpected period %s - %s", sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+		}
+	})
+
+	t.Run("scheduled change is applied on renewal", func(t *testing.T) {
+		sub := &Subscription{Status: StatusActive, PlanID: monthly.ID}
+		sub.StartBillingPeriod(start, end)
+		if err := sub.SchedulePlanChange(yearly.ID, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sub.NextPlanID() != yearly.ID || sub.PlanID != monthly.ID {
+			t.Fatalf("expected pending change to %s while still on %s", yearly.ID, monthly.ID)
+		}
+		if err := sub.Renew(yearly); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sub.PlanID != yearly.ID || sub.PendingPlanID != nil || !sub.CurrentPeriodEnd.Equal(end.AddDate(1, 0, 0)) {
+			t.Errorf("got plan %s pending %v period end %s", sub.PlanID, sub.PendingPlanID, sub.CurrentPeriodEnd)
+		}
+	})
+
+	t.Run("trial stays within the plan family", func(t *testing.T) {
+		basic := &Plan{ID: "basic", Interval: BillingIntervalMonthly, Family: "basic", TrialDays: 7}
+		basicYearly := &Plan{ID: "basic-yearly", Interval: BillingIntervalYearly, Family: "basic"}
+		pro := &Plan{ID: "pro", Interval: BillingIntervalMonthly, Family: "pro"}
+		sub := &Subscription{Status: StatusPending, PlanID: basic.ID}
+		if err := sub.StartTrial(basic, start); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := sub.ChangePlan(basic, pro, now); err != ErrTrialPlanFamilyChange {
+			t.Fatalf("expected ErrTrialPlanFamilyChange, got %v", err)
+		}
+		if err := sub.ChangePlan(basic, basicYearly, now); err != nil || sub.PlanID != basicYearly.ID {
+			t.Errorf("expected a change within the family to be allowed, got plan %s (%v)", sub.PlanID, err)
+		}
+	})
+
+	t.Run("cancelled subscription cannot change plan", func(t *testing.T) {
+		sub := &Subscription{Status: StatusCancelled, PlanID: monthly.ID}
+		if err := sub.ChangePlan(monthly, yearly, now); err != ErrPlanChangeNotAllowed {
+			t.Errorf("expected ErrPlanChangeNotAllowed, got %v", err)
+		}
+	})
+}
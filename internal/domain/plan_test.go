@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanNextPeriodEnd(t *testing.T) {
+	start := time.Date(2025, time.January, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		plan Plan
+		want time.Time
+	}{
+		{"monthly", Plan{Interval: BillingIntervalMonthly}, start.AddDate(0, 1, 0)},
+		{"yearly", Plan{Interval: BillingIntervalYearly}, time.Date(2026, time.January, 31, 10, 0, 0, 0, time.UTC)},
+		{"custom", Plan{Interval: BillingIntervalCustom, IntervalDays: 10}, time.Date(2025, time.February, 10, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.NextPeriodEnd(start); !got.Equal(tt.want) {
+				t.Errorf("NextPeriodEnd() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionRenew(t *testing.T) {
+	end := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	plan := &Plan{Interval: BillingIntervalCustom, IntervalDays: 30}
+
+	sub := &Subscription{Status: StatusActive}
+	sub.StartBillingPeriod(end.AddDate(0, 0, -30), end)
+
+	if !sub.IsDueForRenewal(end) {
+		t.Fatal("expected subscription to be due for renewal")
+	}
+	if err := sub.Renew(plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sub.CurrentPeriodStart.Equal(end) || !sub.CurrentPeriodEnd.Equal(end.AddDate(0, 0, 30)) {
+		t.Errorf("unexpected period %s - %s", sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+	}
+
+	scheduled := &Subscription{Status: StatusActive, CurrentPeriodEnd: &end, CancelAtPeriodEnd: true}
+	if err := scheduled.Renew(plan); err != ErrSubscriptionNotRenewable {
+		t.Errorf("expected ErrSubscriptionNotRenewable for scheduled cancellation, got %v", err)
+	}
+
+	cancelled := &Subscription{Status: StatusCancelled, CurrentPeriodEnd: &end}
+	if err := cancelled.Renew(plan); err != ErrSubscriptionNotRenewable {
+		t.Errorf("expected ErrSubscriptionNotRenewable, got %v", err)
+	}
+}
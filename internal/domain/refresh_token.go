@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenReused indica que um refresh token já revogado foi apresentado novamente.
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+// ErrRefreshTokenNotFound é retornado quando não existe refresh token com o hash procurado.
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshToken é um token de longa duração usado para obter novos access tokens.
+// Apenas o hash é guardado. Todos os tokens emitidos a partir do mesmo login partilham
+// o FamilyID, que também identifica a sessão (claim jti) nos access tokens.
+type RefreshToken struct {
+	ID           string
+	UserID       string
+	FamilyID     string
+	TokenHash    string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	RevokedAt    *time.Time
+	ReplacedByID *string
+}
+
+// IsExpired indica se o token já passou da validade.
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsRevoked indica se o token foi revogado por rotação, logout ou deteção de reutilização.
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
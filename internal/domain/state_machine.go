@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTransition é o erro base para qualquer mudança de estado não permitida.
+var ErrInvalidTransition = errors.New("invalid subscription status transition")
+
+// InvalidTransitionError descreve a transição ilegal que foi tentada.
+type InvalidTransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot transition subscription from %s to %s", e.From, e.To)
+}
+
+// Is permite usar errors.Is(err, ErrInvalidTransition).
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// transitions é a única fonte de verdade sobre os estados alcançáveis a partir de cada estado.
+var transitions = map[Status][]Status{
+	StatusPending:   {StatusTrial, StatusActive, StatusCancelled},
+	StatusTrial:     {StatusActive, StatusCancelled, StatusExpired},
+	StatusActive:    {StatusPastDue, StatusCancelled, StatusPaused},
+	StatusPastDue:   {StatusActive, StatusCancelled},
+	StatusCancelled: {StatusActive},
+	StatusExpired:   {StatusActive},
+	StatusPaused:    {StatusActive, StatusCancelled},
+}
+
+// CanTransition indica se a máquina de estados permite ir de from para to.
+func CanTransition(from, to Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllStatuses lista todos os estados conhecidos pela máquina de estados.
+func AllStatuses() []Status {
+	return []Status{StatusPending, StatusTrial, StatusActive, StatusPastDue, StatusCancelled, StatusExpired, StatusPaused}
+}
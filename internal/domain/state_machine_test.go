@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransitionMatrix(t *testing.T) {
+	allowed := map[Status]map[Status]bool{
+		StatusPending:   {StatusTrial: true, StatusActive: true, StatusCancelled: true},
+		StatusTrial:     {StatusActive: true, StatusCancelled: true, StatusExpired: true},
+		StatusActive:    {StatusPastDue: true, StatusCancelled: true, StatusPaused: true},
+		StatusPastDue:   {StatusActive: true, StatusCancelled: true},
+		StatusCancelled: {StatusActive: true},
+		StatusExpired:   {StatusActive: true},
+		StatusPaused:    {StatusActive: true, StatusCancelled: true},
+	}
+
+	for _, from := range AllStatuses() {
+		for _, to := range AllStatuses() {
+			want := allowed[from][to]
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				if got := CanTransition(from, to); got != want {
+					t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+				}
+			})
+		}
+	}
+}
+
+// startDunning é o caminho de produção para PAST_DUE: uma renovação recusada inicia o dunning.
+func startDunning(s *Subscription) error {
+	return s.StartDunning(time.Now(), DunningScheduleFromDays([]int{1, 3}))
+}
+
+func TestSubscriptionTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    Status
+		action  func(s *Subscription) error
+		want    Status
+		wantErr bool
+	}{
+		{"pending starts trial", StatusPending, func(s *Subscription) error { return s.StartTrial(&Plan{TrialDays: 7}, time.Now()) }, StatusTrial, false},
+		{"pending activates", StatusPending, (*Subscription).Activate, StatusActive, false},
+		{"pending starts without trial", StatusPending, func(s *Subscription) error { return s.StartFirstPeriod(&Plan{}, time.Now()) }, StatusActive, false},
+		{"pending cancels", StatusPending, (*Subscription).Cancel, StatusCancelled, false},
+		{"pending cannot become past due", StatusPending, startDunning, StatusPending, true},
+		{"trial activates", StatusTrial, (*Subscription).Activate, StatusActive, false},
+		{"trial cancels", StatusTrial, (*Subscription).Cancel, StatusCancelled, false},
+		{"trial expires", StatusTrial, (*Subscription).Expire, StatusExpired, false},
+		{"trial cannot restart trial", StatusTrial, func(s *Subscription) error { return s.StartTrial(&Plan{TrialDays: 7}, time.Now()) }, StatusTrial, true},
+		{"trial cannot become past due", StatusTrial, startDunning, StatusTrial, true},
+		{"active becomes past due", StatusActive, startDunning, StatusPastDue, false},
+		{"active cancels", StatusActive, (*Subscription).Cancel, StatusCancelled, false},
+		{"active cannot start trial", StatusActive, func(s *Subscription) error { return s.StartTrial(&Plan{TrialDays: 7}, time.Now()) }, StatusActive, true},
+		{"past due recovers", StatusPastDue, (*Subscription).Activate, StatusActive, false},
+		{"past due cancels", StatusPastDue, (*Subscription).Cancel, StatusCancelled, false},
+		{"cancelled is reactivated", StatusCancelled, func(s *Subscription) error { return s.Reactivate(&Plan{Interval: BillingIntervalMonthly}) }, StatusActive, false},
+		{"cancelled cannot become past due", StatusCancelled, startDunning, StatusCancelled, true},
+		{"cancelled cannot cancel again", StatusCancelled, (*Subscription).Cancel, StatusCancelled, true},
+		{"active cannot expire", StatusActive, (*Subscription).Expire, StatusActive, true},
+		{"expired is reactivated", StatusExpired, func(s *Subscription) error { return s.Reactivate(&Plan{Interval: BillingIntervalMonthly}) }, StatusActive, false},
+		{"expired cannot start trial", StatusExpired, func(s *Subscription) error { return s.StartTrial(&Plan{TrialDays: 7}, time.Now()) }, StatusExpired, true},
+		{"active pauses", StatusActive, func(s *Subscription) error { return s.Pause(time.Now(), nil) }, StatusPaused, false},
+		{"trial cannot pause", StatusTrial, func(s *Subscription) error { return s.Pause(time.Now(), nil) }, StatusTrial, true},
+		{"paused resumes", StatusPaused, func(s *Subscription) error { return s.Resume(time.Now()) }, StatusActive, false},
+		{"paused cancels", StatusPaused, (*Subscription).Cancel, StatusCancelled, false},
+		{"paused cannot become past due", StatusPaused, startDunning, StatusPaused, true},
+		{"active cannot resume", StatusActive, func(s *Subscription) error { return s.Resume(time.Now()) }, StatusActive, true},
+		{"active cannot be reactivated", StatusActive, func(s *Subscription) error { return s.Reactivate(&Plan{Interval: BillingIntervalMonthly}) }, StatusActive, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Subscription{Status: tt.from}
+			err := tt.action(sub)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("expected ErrInvalidTransition, got %v", err)
+				}
+				var transitionErr *InvalidTransitionError
+				if !errors.As(err, &transitionErr) || transitionErr.From != tt.from {
+					t.Fatalf("expected InvalidTransitionError from %s, got %v", tt.from, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sub.Status != tt.want {
+				t.Errorf("status = %s, want %s", sub.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancelSetsCancelledAt(t *testing.T) {
+	sub := &Subscription{Status: StatusActive}
+	if err := sub.Cancel(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.CancelledAt == nil {
+		t.Fatal("expected CancelledAt to be set")
+	}
+}
+
+func TestScheduledCancellation(t *testing.T) {
+	now := time.Now()
+	end := now.AddDate(0, 1, 0)
+
+	sub := &Subscription{Status: StatusActive, CurrentPeriodEnd: &end}
+	if err := sub.UndoScheduledCancellation(now); err != ErrNoScheduledCancellation {
+		t.Fatalf("expected ErrNoScheduledCancellation, got %v", err)
+	}
+	if err := sub.ScheduleCancellation(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Status != StatusActive || !sub.CancelAtPeriodEnd {
+		t.Fatalf("expected active subscription with scheduled cancellation, got %s / %v", sub.Status, sub.CancelAtPeriodEnd)
+	}
+	if err := sub.UndoScheduledCancellation(now); err != nil || sub.CancelAtPeriodEnd {
+		t.Fatalf("expected scheduled cancellation to be undone, got %v", err)
+	}
+
+	trial := &Subscription{Status: StatusTrial, CurrentPeriodEnd: &end}
+	if err := trial.ScheduleCancellation(now); err != ErrSubscriptionCannotBeCancelled {
+		t.Errorf("expected ErrSubscriptionCannotBeCancelled for trial, got %v", err)
+	}
+}
+
+func TestResumeExtendsBillingPeriodByPausedTime(t *testing.T) {
+	start := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	pausedAt := start.AddDate(0, 0, 10)
+	resumedAt := pausedAt.AddDate(0, 0, 7)
+
+	sub := &Subscription{Status: StatusActive}
+	sub.StartBillingPeriod(start, end)
+
+	if err := sub.Pause(pausedAt, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.IsDueForRenewal(end) {
+		t.Error("paused subscription must not be renewed")
+	}
+	if err := sub.Resume(resumedAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := end.AddDate(0, 0, 7); !sub.CurrentPeriodEnd.Equal(want) {
+		t.Errorf("period end = %s, want %s", sub.CurrentPeriodEnd, want)
+	}
+	if sub.PausedAt != nil || sub.ResumesAt != nil {
+		t.Error("expected pause fields to be cleared")
+	}
+}
+
+func TestPauseRejectsPastResumeDate(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	sub := &Subscription{Status: StatusActive}
+	if err := sub.Pause(now, &past); err != ErrInvalidResumeDate {
+		t.Fatalf("expected ErrInvalidResumeDate, got %v", err)
+	}
+	if sub.Status != StatusActive {
+		t.Errorf("status = %s, want %s", sub.Status, StatusActive)
+	}
+
+	future := now.Add(time.Hour)
+	if err := sub.Pause(now, &future); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.IsDueForResume(now) || !sub.IsDueForResume(future) {
+		t.Error("IsDueForResume should only report true once ResumesAt is reached")
+	}
+}
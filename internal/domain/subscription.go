@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -11,40 +12,326 @@ var ErrSubscriptionNotFound = errors.New("subscription not found")
 // ErrSubscriptionCannotBeCancelled é um erro para quando uma ação de cancelamento é inválida.
 var ErrSubscriptionCannotBeCancelled = errors.New("subscription cannot be cancelled")
 
+// ErrSubscriptionNotRenewable é retornado quando se tenta renovar uma assinatura que não está ativa.
+var ErrSubscriptionNotRenewable = errors.New("subscription cannot be renewed")
+
 // ErrForbidden é usado quando um utilizador tenta aceder a um recurso que não lhe pertence.
 var ErrForbidden = errors.New("user does not have permission to access this resource")
 
+// ErrNoScheduledCancellation é retornado ao tentar desfazer um cancelamento que não foi agendado.
+var ErrNoScheduledCancellation = errors.New("subscription has no scheduled cancellation")
+
+// ErrInvalidResumeDate é retornado quando a data de retoma agendada não está no futuro.
+var ErrInvalidResumeDate = errors.New("resume date must be in the future")
+
+// ErrVersionConflict é o erro base para gravações feitas sobre uma versão desatualizada da assinatura.
+var ErrVersionConflict = errors.New("subscription was modified concurrently")
+
+// VersionConflictError indica que a assinatura foi alterada por outro processo desde que foi lida.
+type VersionConflictError struct {
+	SubscriptionID string
+	Version        int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("subscription %s was modified concurrently (expected version %d)", e.SubscriptionID, e.Version)
+}
+
+// Is permite usar errors.Is(err, ErrVersionConflict).
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
 // Status define os possíveis estados de uma assinatura.
 type Status string
 
 const (
+	StatusPending   Status = "PENDING"
 	StatusActive    Status = "ACTIVE"
 	StatusTrial     Status = "TRIAL"
 	StatusPastDue   Status = "PAST_DUE"
 	StatusCancelled Status = "CANCELLED"
+	StatusExpired   Status = "EXPIRED"
+	StatusPaused    Status = "PAUSED"
 )
 
 // Subscription é a entidade central do nosso domínio.
 type Subscription struct {
-	ID          string     `json:"id"`
-	UserID      string     `json:"userId"`
-	PlanID      string     `json:"planId"`
-	Status      Status     `json:"status"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
-	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
-	TrialEndsAt *time.Time `json:"trialEndsAt,omitempty"`
+	ID                 string     `json:"id"`
+	UserID             string     `json:"userId"`
+	PlanID             string     `json:"planId"`
+	Status             Status     `json:"status"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+	CancelledAt        *time.Time `json:"cancelledAt,omitempty"`
+	TrialEndsAt        *time.Time `json:"trialEndsAt,omitempty"`
+	CurrentPeriodStart *time.Time `json:"currentPeriodStart,omitempty"`
+	CurrentPeriodEnd   *time.Time `json:"currentPeriodEnd,omitempty"`
+	PaymentMethodID    *string    `json:"paymentMethodId,omitempty"`
+	CancelAtPeriodEnd  bool       `json:"cancelAtPeriodEnd"`
+	PastDueSince       *time.Time `json:"pastDueSince,omitempty"`
+	DunningAttempts    int        `json:"dunningAttempts,omitempty"`
+	NextDunningAt      *time.Time `json:"nextDunningAt,omitempty"`
+	PausedAt           *time.Time `json:"pausedAt,omitempty"`
+	ResumesAt          *time.Time `json:"resumesAt,omitempty"`
+	// PendingPlanID é o plano agendado para entrar em vigor na próxima renovação.
+	PendingPlanID *string `json:"pendingPlanId,omitempty"`
+	// Discount é o desconto do cupom resgatado na criação, enquanto durar.
+	Discount *Discount `json:"discount,omitempty"`
+	// CreditBalance é o crédito a favor do cliente, abatido nas próximas faturas.
+	CreditBalance int64 `json:"creditBalance"`
+	// Version é incrementada a cada gravação e usada para detetar escritas concorrentes.
+	// Zero indica uma assinatura que ainda não foi persistida.
+	Version int `json:"version"`
+
+	TrialReminderSentAt *time.Time `json:"-"`
+	// TrialPlanFamily é a família do plano em que o trial foi concedido.
+	TrialPlanFamily *string `json:"-"`
 }
 
 // CanBeCancelled é um exemplo de regra de negócio dentro do domínio.
 func (s *Subscription) CanBeCancelled() bool {
-	return s.Status == StatusActive || s.Status == StatusTrial
+	return CanTransition(s.Status, StatusCancelled)
+}
+
+// transitionTo é o único ponto onde o Status é alterado; toda mudança passa pela máquina de estados.
+func (s *Subscription) transitionTo(to Status, now time.Time) error {
+	if !CanTransition(s.Status, to) {
+		return &InvalidTransitionError{From: s.Status, To: to}
+	}
+	s.Status = to
+	s.UpdatedAt = now
+	return nil
+}
+
+// StartTrial coloca uma assinatura pendente em período de avaliação de p.TrialDays dias e
+// regista a família do plano, para que o utilizador não tenha outro trial nela.
+func (s *Subscription) StartTrial(p *Plan, now time.Time) error {
+	if err := s.transitionTo(StatusTrial, now); err != nil {
+		return err
+	}
+	endsAt := now.AddDate(0, 0, p.TrialDays)
+	family := p.Family
+	s.TrialEndsAt = &endsAt
+	s.TrialPlanFamily = &family
+	s.StartBillingPeriod(now, endsAt)
+	return nil
+}
+
+// StartFirstPeriod ativa uma assinatura pendente sem trial, com o primeiro período pago a começar em now.
+func (s *Subscription) StartFirstPeriod(p *Plan, now time.Time) error {
+	if err := s.transitionTo(StatusActive, now); err != nil {
+		return err
+	}
+	s.StartBillingPeriod(now, p.NextPeriodEnd(now))
+	return nil
+}
+
+// Activate move a assinatura para o estado ativo.
+func (s *Subscription) Activate() error {
+	return s.transitionTo(StatusActive, time.Now().UTC())
+}
+
+// Expire encerra uma assinatura cujo período de avaliação terminou sem conversão.
+func (s *Subscription) Expire() error {
+	return s.transitionTo(StatusExpired, time.Now().UTC())
 }
 
 // Cancel move a assinatura para o estado de cancelada.
-func (s *Subscription) Cancel() {
+func (s *Subscription) Cancel() error {
 	now := time.Now().UTC()
-	s.Status = StatusCancelled
+	if err := s.transitionTo(StatusCancelled, now); err != nil {
+		return err
+	}
 	s.CancelledAt = &now
+	s.CancelAtPeriodEnd = false
+	s.clearDunning()
+	s.PausedAt = nil
+	s.ResumesAt = nil
+	return nil
+}
+
+// ScheduleCancellation agenda o cancelamento para o fim do período corrente; até lá a
+// assinatura continua ativa e pode ser reativada com UndoScheduledCancellation.
+func (s *Subscription) ScheduleCancellation(now time.Time) error {
+	if s.Status != StatusActive || s.CurrentPeriodEnd == nil {
+		return ErrSubscriptionCannotBeCancelled
+	}
+	s.CancelAtPeriodEnd = true
+	s.UpdatedAt = now
+	return nil
+}
+
+// UndoScheduledCancellation desfaz um cancelamento agendado que ainda não entrou em vigor.
+func (s *Subscription) UndoScheduledCancellation(now time.Time) error {
+	if s.Status != StatusActive || !s.CancelAtPeriodEnd {
+		return ErrNoScheduledCancellation
+	}
+	s.CancelAtPeriodEnd = false
+	s.UpdatedAt = now
+	return nil
+}
+
+// StartBillingPeriod define o período de cobrança corrente.
+func (s *Subscription) StartBillingPeriod(start, end time.Time) {
+	s.CurrentPeriodStart = &start
+	s.CurrentPeriodEnd = &end
+}
+
+// IsDueForRenewal indica se o período corrente de uma assinatura ativa já terminou.
+func (s *Subscription) IsDueForRenewal(now time.Time) bool {
+	return s.Status == StatusActive && s.CurrentPeriodEnd != nil && !s.CurrentPeriodEnd.After(now)
+}
+
+// NextPlanID devolve o plano do próximo período: o agendado, se houver, ou o atual.
+func (s *Subscription) NextPlanID() string {
+	if s.PendingPlanID != nil {
+		return *s.PendingPlanID
+	}
+	return s.PlanID
+}
+
+// Renew avança a assinatura para o próximo período de cobrança de acordo com o intervalo do plano.
+// p deve ser o plano devolvido por NextPlanID, aplicando assim uma troca agendada.
+// Assinaturas com cancelamento agendado não são renovadas.
+func (s *Subscription) Renew(p *Plan) error {
+	if s.Status != StatusActive || s.CurrentPeriodEnd == nil || s.CancelAtPeriodEnd {
+		return ErrSubscriptionNotRenewable
+	}
+	s.PlanID = p.ID
+	s.PendingPlanID = nil
+	start := *s.CurrentPeriodEnd
+	s.StartBillingPeriod(start, p.NextPeriodEnd(start))
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// HasPaymentMethod indica se a assinatura já tem um meio de pagamento associado.
+func (s *Subscription) HasPaymentMethod() bool {
+	return s.PaymentMethodID != nil && *s.PaymentMethodID != ""
+}
+
+// AttachPaymentMethod associa o meio de pagamento usado nas cobranças da assinatura.
+func (s *Subscription) AttachPaymentMethod(paymentMethodID string) {
+	s.PaymentMethodID = &paymentMethodID
+	s.UpdatedAt = time.Now().UTC()
+}
+
+// ConvertTrial ativa uma assinatura em avaliação, iniciando o primeiro período pago no fim do trial.
+func (s *Subscription) ConvertTrial(p *Plan) error {
+	if err := s.Activate(); err != nil {
+		return err
+	}
+	start := s.UpdatedAt
+	if s.TrialEndsAt != nil {
+		start = *s.TrialEndsAt
+	}
+	s.StartBillingPeriod(start, p.NextPeriodEnd(start))
+	return nil
+}
+
+// CanReactivate indica se a assinatura está cancelada ou expirada e pode ser reaberta.
+func (s *Subscription) CanReactivate() bool {
+	return s.Status == StatusCancelled || s.Status == StatusExpired
+}
+
+// Reactivate reabre uma assinatura cancelada ou expirada, iniciando um novo período de cobrança.
+func (s *Subscription) Reactivate(p *Plan) error {
+	if !s.CanReactivate() {
+		return &InvalidTransitionError{From: s.Status, To: StatusActive}
+	}
+	if err := s.Activate(); err != nil {
+		return err
+	}
+	s.CancelledAt = nil
+	s.StartBillingPeriod(s.UpdatedAt, p.NextPeriodEnd(s.UpdatedAt))
+	return nil
+}
+
+// Pause suspende uma assinatura ativa. Se resumesAt for informado, a assinatura será retomada
+// automaticamente nessa data; caso contrário fica pausada até ser retomada manualmente.
+func (s *Subscription) Pause(now time.Time, resumesAt *time.Time) error {
+	if resumesAt != nil && !resumesAt.After(now) {
+		return ErrInvalidResumeDate
+	}
+	if err := s.transitionTo(StatusPaused, now); err != nil {
+		return err
+	}
+	s.PausedAt = &now
+	s.ResumesAt = resumesAt
+	return nil
+}
+
+// Resume reativa uma assinatura pausada, estendendo o período corrente pelo tempo em pausa
+// para que esse tempo não seja cobrado.
+func (s *Subscription) Resume(now time.Time) error {
+	if err := s.transitionTo(StatusActive, now); err != nil {
+		return err
+	}
+	if s.PausedAt != nil && s.CurrentPeriodEnd != nil {
+		end := s.CurrentPeriodEnd.Add(now.Sub(*s.PausedAt))
+		s.CurrentPeriodEnd = &end
+	}
+	s.PausedAt = nil
+	s.ResumesAt = nil
+	return nil
+}
+
+// IsDueForResume indica se uma assinatura pausada já atingiu a data de retoma agendada.
+func (s *Subscription) IsDueForResume(now time.Time) bool {
+	return s.Status == StatusPaused && s.ResumesAt != nil && !s.ResumesAt.After(now)
+}
+
+// CanChangePlan indica se a assinatura está num estado que permite trocar de plano.
+func (s *Subscription) CanChangePlan() bool {
+	return s.Status == StatusActive || s.Status == StatusTrial
+}
+
+// ChangePlan troca o plano imediatamente. Em assinaturas ativas, se o ciclo de cobrança mudar,
+// inicia-se um novo período em now; durante o trial o período de avaliação é mantido.
+func (s *Subscription) ChangePlan(current, next *Plan, now time.Time) error {
+	if !s.CanChangePlan() {
+		return ErrPlanChangeNotAllowed
+	}
+	if s.Status == StatusTrial && next.Family != s.trialFamily(current) {
+		return ErrTrialPlanFamilyChange
+	}
+	s.PlanID = next.ID
+	s.PendingPlanID = nil
+	if s.Status == StatusActive && !current.SameBillingCycle(next) {
+		s.StartBillingPeriod(now, next.NextPeriodEnd(now))
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// trialFamily devolve a família em que o trial foi concedido; trials anteriores ao registo da
+// família assumem a do plano atual.
+func (s *Subscription) trialFamily(current *Plan) string {
+	if s.TrialPlanFamily != nil {
+		return *s.TrialPlanFamily
+	}
+	return current.Family
+}
+
+// SchedulePlanChange agenda a troca de plano para o fim do período corrente.
+func (s *Subscription) SchedulePlanChange(planID string, now time.Time) error {
+	if s.Status != StatusActive {
+		return ErrPlanChangeNotAllowed
+	}
+	s.PendingPlanID = &planID
+	s.UpdatedAt = now
+	return nil
+}
+
+// ClearPendingPlanChange desfaz uma troca de plano agendada.
+func (s *Subscription) ClearPendingPlanChange(now time.Time) {
+	s.PendingPlanID = nil
+	s.UpdatedAt = now
+}
+
+// MarkTrialReminderSent regista que o aviso de fim de trial já foi enviado.
+func (s *Subscription) MarkTrialReminderSent(now time.Time) {
+	s.TrialReminderSentAt = &now
 	s.UpdatedAt = now
 }
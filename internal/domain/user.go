@@ -1,6 +1,21 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrUserNotFound é retornado quando o utilizador procurado não existe.
+var ErrUserNotFound = errors.New("user not found")
+
+// Role define o nível de acesso de um utilizador.
+type Role string
+
+const (
+	RoleCustomer Role = "CUSTOMER"
+	RoleSupport  Role = "SUPPORT"
+	RoleAdmin    Role = "ADMIN"
+)
 
 // User representa um usuário no sistema.
 type User struct {
@@ -8,6 +23,10 @@ type User struct {
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"` // O hífen faz com que este campo NUNCA seja exposto em JSON
+	Role         Role      `json:"role"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
+
+	// PaymentCustomerID é o ID do cliente no provedor de pagamentos, criado na primeira cobrança.
+	PaymentCustomerID *string `json:"-"`
 }